@@ -0,0 +1,132 @@
+// protoc-gen-sol by Celer Network Team
+
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// path numbers of SourceCodeInfo.Location, per field numbers in descriptor.proto
+const (
+	fileMessagePath = 4 // FileDescriptorProto.message_type
+	fileEnumPath    = 5 // FileDescriptorProto.enum_type
+	msgFieldPath    = 2 // DescriptorProto.field
+	enumValuePath   = 2 // EnumDescriptorProto.value
+)
+
+// comment is leading and trailing comment lines of a proto element, detached comments are ignored
+type comment struct {
+	leading, trailing []string
+}
+
+// findComments returns comments of top level messages, their fields, enums and enum values in files,
+// key is the descriptor pointer eg. *descriptor.FieldDescriptorProto. files without SourceCodeInfo have none
+func findComments(files []*descriptor.FileDescriptorProto) map[interface{}]comment {
+	ret := make(map[interface{}]comment)
+	for _, f := range files {
+		for _, loc := range f.GetSourceCodeInfo().GetLocation() {
+			c := comment{commentLines(loc.GetLeadingComments()), commentLines(loc.GetTrailingComments())}
+			if len(c.leading) == 0 && len(c.trailing) == 0 {
+				continue
+			}
+			if key := commentKey(f, loc.Path); key != nil {
+				ret[key] = c
+			}
+		}
+	}
+	return ret
+}
+
+// commentKey returns descriptor of path in f, nil if it's not a top level message, field, enum or enum value
+func commentKey(f *descriptor.FileDescriptorProto, path []int32) interface{} {
+	if len(path) != 2 && len(path) != 4 {
+		return nil
+	}
+	if len(path) == 4 && path[2] != msgFieldPath { // msgFieldPath == enumValuePath
+		return nil
+	}
+	i := int(path[1])
+	switch path[0] {
+	case fileMessagePath:
+		if i >= len(f.MessageType) {
+			return nil
+		}
+		m := f.MessageType[i]
+		if len(path) == 2 {
+			return m
+		}
+		if j := int(path[3]); j < len(m.Field) {
+			return m.Field[j]
+		}
+	case fileEnumPath:
+		if i >= len(f.EnumType) {
+			return nil
+		}
+		e := f.EnumType[i]
+		if len(path) == 2 {
+			return e
+		}
+		if j := int(path[3]); j < len(e.Value) {
+			return e.Value[j]
+		}
+	}
+	return nil
+}
+
+// commentLines splits proto comment into trimmed lines, without leading and trailing empty lines
+func commentLines(s string) (lines []string) {
+	for _, l := range strings.Split(strings.TrimSpace(s), "\n") {
+		lines = append(lines, strings.TrimSpace(l))
+	}
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	return
+}
+
+// natspec generates NatSpec of a struct, enum or function, leading comment is @notice and trailing is @dev
+func (g *Generator) natspec(c comment) {
+	natspecTag(g, "@notice", c.leading)
+	natspecTag(g, "@dev", c.trailing)
+}
+
+// natspecTag generates NatSpec tag of comment lines joined in one line, solc parses @ at the start of
+// or inside a following line as a new tag, eg. "@param foo" in comment fails as param isn't found
+func natspecTag(g *Generator, tag string, lines []string) {
+	if len(lines) > 0 {
+		g.P("/// ", tag, " ", strings.Join(lines, " "))
+	}
+}
+
+// memberComment generates leading comment of struct member or enum value as plain comment lines,
+// as solc doesn't accept NatSpec on them. trailing comment is returned to be appended to the member line
+func (g *Generator) memberComment(c comment) string {
+	for _, l := range c.leading {
+		g.P(strings.TrimRight("// "+l, " "))
+	}
+	return strings.Join(c.trailing, " ")
+}
+
+// fieldComment returns comment of struct member, including tag number, proto type and soltype if set
+func (g *Generator) fieldComment(f *descriptor.FieldDescriptorProto) string {
+	s := "// tag: " + fmt.Sprint(*f.Number) + ", proto: " + protoType(f)
+	if opt := g.getSolTypeOpt(f); opt != "" {
+		s += ", soltype: " + opt
+	}
+	return s
+}
+
+// protoType returns proto type of field as in .proto file, eg. repeated uint64 or pkg.Msg
+func protoType(f *descriptor.FieldDescriptorProto) string {
+	t := strings.ToLower(strings.TrimPrefix(f.GetType().String(), "TYPE_"))
+	if f.TypeName != nil {
+		t = strings.TrimPrefix(*f.TypeName, ".")
+	}
+	if isRepeated(f) {
+		t = "repeated " + t
+	}
+	return t
+}
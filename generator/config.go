@@ -0,0 +1,188 @@
+// protoc-gen-sol by Celer Network Team
+
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// ParamNames are all supported params, see ParseParams for details
+var ParamNames = []string{"config", "msg", "exclude", "importpb", "solc", "spdx", "pragma", "extrapragma", "extraimport",
+	"provenance", "pblib", "libprefix", "libsuffix", "importprefix", "naming", "stripenumprefix", "enumutils",
+	"timenanos", "anyhelpers", "skipdeprecated", "typeurlprefix", "doc"}
+
+// BoolParams are params of true/false value, they can be set as bare flag eg. importpb is importpb=true
+var BoolParams = toSet(`importpb provenance stripenumprefix enumutils timenanos anyhelpers skipdeprecated doc`)
+
+// RepeatedParams are params that can be specified multiple times, or as array in config file
+var RepeatedParams = toSet(`msg exclude extrapragma extraimport`)
+
+// param is a key=value in plugin parameter, key may have @scope
+type param struct {
+	key, value string
+}
+
+// splitParams splits plugin parameter by comma into params. comma or backslash in value can be
+// escaped by backslash eg. extraimport=a\,b.sol. empty param is ignored, and bool param without value is true
+func splitParams(parameter string) (params []param) {
+	var parts []string
+	var cur strings.Builder
+	for i := 0; i < len(parameter); i++ {
+		c := parameter[i]
+		if c == '\\' && i+1 < len(parameter) && (parameter[i+1] == ',' || parameter[i+1] == '\\') {
+			cur.WriteByte(parameter[i+1])
+			i++
+		} else if c == ',' {
+			parts = append(parts, cur.String())
+			cur.Reset()
+		} else {
+			cur.WriteByte(c)
+		}
+	}
+	parts = append(parts, cur.String())
+	for _, p := range parts {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		tmp := strings.SplitN(p, "=", 2) // value may have = like pragma=>=0.8.0
+		key := strings.TrimSpace(tmp[0])
+		if len(tmp) == 2 {
+			params = append(params, param{key, tmp[1]})
+		} else if BoolParams[strings.SplitN(key, "@", 2)[0]] {
+			params = append(params, param{key, "true"})
+		} else {
+			Fail("invalid param", p, "expect key=value")
+		}
+	}
+	return
+}
+
+// nonEmpty returns value of param key, fails if it's empty
+func nonEmpty(key, value string) string {
+	if strings.TrimSpace(value) == "" {
+		Fail("empty value of param", key)
+	}
+	return value
+}
+
+// isParam returns whether key is a supported param
+func isParam(key string) bool {
+	for _, k := range ParamNames {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// parseBool returns bool value of bool param key, fails if it isn't true or false
+func parseBool(key, value string) bool {
+	if value != "true" && value != "false" {
+		Fail("invalid value", value, "of param", key, "expect true or false")
+	}
+	return value == "true"
+}
+
+// loadConfig sets params in json config file at path, which is an object of param to its value,
+// eg. {"solc": "0.8", "msg": ["Msg1", "Msg2"], "importpb": true}. value is string, bool, number,
+// or array of them for RepeatedParams. scoped params are in "scopes", an object of package or .proto
+// file name to its params, eg. {"scopes": {"mytest": {"naming": "snake"}}}
+func (g *Generator) loadConfig(path string) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		Error(err, "failed to read config", path)
+	}
+	var cfg map[string]json.RawMessage
+	if err := decodeJSON(data, &cfg); err != nil {
+		Error(err, "invalid config", path, "expect json object of param to value")
+	}
+	for _, key := range sortedKeys(cfg) {
+		if key == "scopes" {
+			var scopes map[string]map[string]json.RawMessage
+			if err := decodeJSON(cfg[key], &scopes); err != nil {
+				Error(err, "invalid scopes in config", path, "expect object of package or .proto file to params")
+			}
+			for _, scope := range sortedScopes(scopes) {
+				for _, k := range sortedKeys(scopes[scope]) {
+					for _, v := range configValues(path, k, scopes[scope][k]) {
+						g.addParam(k+"@"+scope, v)
+					}
+				}
+			}
+			continue
+		}
+		for _, v := range configValues(path, key, cfg[key]) {
+			g.addParam(key, v)
+		}
+	}
+}
+
+// configValues returns param values of key in config file at path
+func configValues(path, key string, raw json.RawMessage) (values []string) {
+	if !isParam(key) || key == "config" {
+		Fail("unknown param", key, "in config", path+",", "valid params are", strings.Join(ParamNames[1:], ", "), "and scopes")
+	}
+	var v interface{}
+	if err := decodeJSON(raw, &v); err != nil {
+		Error(err, "invalid value of", key, "in config", path)
+	}
+	arr, isArr := v.([]interface{})
+	if !isArr {
+		arr = []interface{}{v}
+	} else if !RepeatedParams[key] {
+		Fail("param", key, "in config", path, "can't be array, only", "msg, exclude, extrapragma and extraimport can")
+	}
+	for _, e := range arr {
+		switch e := e.(type) {
+		case string:
+			values = append(values, e)
+		case bool:
+			if e {
+				values = append(values, "true")
+			} else {
+				values = append(values, "false")
+			}
+		case json.Number:
+			values = append(values, e.String()) // keep original text eg. solc 0.8
+		default:
+			Fail("invalid value of", key, "in config", path, "expect string, bool, number or array of them")
+		}
+	}
+	return
+}
+
+// decodeJSON decodes data into v, numbers are kept as json.Number. data must be exactly one json value
+func decodeJSON(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	var extra json.RawMessage
+	if err := dec.Decode(&extra); err != io.EOF {
+		return errors.New("unexpected data after json value")
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]json.RawMessage) (keys []string) {
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return
+}
+
+func sortedScopes(m map[string]map[string]json.RawMessage) (keys []string) {
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return
+}
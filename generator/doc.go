@@ -0,0 +1,154 @@
+// protoc-gen-sol by Celer Network Team
+
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// generateDoc generates markdown reference of the solidity mapping of files in one package, eg. for
+// front-end and audit. types and decode code come from getSolType and getSolDecodeStr so they're the
+// same as in generated .sol. must be called after generate(files) as it relies on curPkg and checkNames
+func (g *Generator) generateDoc(files []fdes) {
+	var names []string
+	for _, f := range files {
+		names = append(names, *f.Name)
+	}
+	lib := g.getSolLib(*files[0].Package)
+	g.P("# ", lib)
+	g.P()
+	g.P("Code generated by protoc-gen-sol. DO NOT EDIT.")
+	g.P()
+	g.P("Solidity library `", lib, "` in `", g.getSolFile(*files[0].Package), "`, generated from package `", *files[0].Package,
+		"` in ", strings.Join(names, ", "), ". Runtime library is `", g.pblib, "`.")
+	for _, f := range files {
+		for _, e := range f.EnumType {
+			if g.shouldOutput(*f.Package, *e.Name) {
+				g.generateEnumDoc(lib, e)
+			}
+		}
+	}
+	for _, f := range files {
+		for _, m := range f.MessageType {
+			if g.shouldOutput(*f.Package, *m.Name) {
+				g.generateMsgDoc(lib, m)
+			}
+		}
+	}
+}
+
+// generateEnumDoc generates markdown of enum e, its members and conversion functions
+func (g *Generator) generateEnumDoc(lib string, e enumdes) {
+	name := enumName(e)
+	g.P()
+	g.P("## enum ", name)
+	g.P()
+	g.P("Proto enum `", curPkg, ".", *e.Name, "`.")
+	g.docComment(g.comments[(*descriptor.EnumDescriptorProto)(e)])
+	g.P()
+	g.P("| Solidity member | Proto value | Number | Comment |")
+	g.P("| --- | --- | --- | --- |")
+	names := g.enumValueNames(e)
+	for _, i := range enumMembers(e) {
+		v := e.Value[i]
+		g.P("| `", names[i], "` | `", *v.Name, "` | ", *v.Number, " | ", docCell(g.comments[v]), " |")
+	}
+	g.P()
+	g.P("Functions in library `", lib, "`:")
+	g.P()
+	g.P("- `", fromNumberName(name), "(uint num) returns (", name, ")`: proto number to enum, reverts if unknown")
+	if g.hasToNumber(e) {
+		g.P("- `", toNumberName(name), "(", name, " v) returns (uint)`: enum to proto number")
+	}
+	g.P("- `", orUnknownName(name), "(uint num) returns (", name, ")`: proto number to enum, unknown number is the value of number 0")
+	if g.enumUtils {
+		fns := enumUtilNames(name)
+		g.P("- `", fns[0], "(uint num) returns (bool)`: whether num is a proto number of the enum")
+		g.P("- `", fns[1], "() returns (uint)`: number of members")
+		g.P("- `", fns[2], "(", name, " v) returns (string)`: proto value name")
+	}
+	g.P("- `", name, "s(uint[] arr) returns (", name, "[])`: decode packed proto numbers, as `", enumConv(e, name, "arr[i]", enumMode(e)), "` of each element")
+}
+
+// generateMsgDoc generates markdown of message m, its struct members and decoder
+func (g *Generator) generateMsgDoc(lib string, m msgdes) {
+	name := msgName(m)
+	g.P()
+	g.P("## struct ", name)
+	g.P()
+	g.P("Proto message `", curPkg, ".", *m.Name, "`, decoded by `", lib, ".", getDecFname(name), "(bytes raw) returns (", name, ")`.")
+	g.docComment(g.comments[(*descriptor.DescriptorProto)(m)])
+	g.P()
+	g.P("| Tag | Member | Proto type | soltype | Solidity type | Label | Decode | Comment |")
+	g.P("| --- | --- | --- | --- | --- | --- | --- | --- |")
+	fields := g.solFields(m)
+	for _, f := range fields {
+		t := g.getSolType(f)
+		g.P("| ", f.Number, " | `", g.fieldName(f), "` | `", protoType(f), "` | ", docCode(g.getSolTypeOpt(f)), " | `", t, "` | ",
+			fieldLabel(f), " | ", docCode(g.getSolDecodeStr(f, t)), " | ", docCell(g.comments[f]), " |")
+		if g.isRawEnum(f) {
+			g.P("| ", f.Number, " | `", g.rawFieldName(f), "` | | | `", g.rawSolType(f), "` | | | raw enum number of `", g.fieldName(f), "` |")
+		}
+		if hasPresence(f) {
+			g.P("| ", f.Number, " | `", g.presenceName(f), "` | | | `bool` | | | whether `", g.fieldName(f), "` is set |")
+		}
+	}
+	if len(fields) == 0 {
+		g.P("| | `present` | | | `bool` | | | placeholder, set true by decoder |")
+	}
+	var skipped []string
+	for _, f := range m.Field {
+		if g.isSkipped(f) {
+			skipped = append(skipped, fmt.Sprintf("`%s` (tag %d)", *f.Name, *f.Number))
+		}
+	}
+	if len(skipped) > 0 {
+		g.P()
+		g.P("Skipped fields, decoded as unknown fields: ", strings.Join(skipped, ", "), ".")
+	}
+	if g.anyHelpers {
+		fns := anyHelperNames(name)
+		g.P()
+		g.P("`google.protobuf.Any` helpers in library `", lib, "`: `", strings.Join(fns, "`, `"), "`.")
+	}
+}
+
+// docComment generates proto comment as markdown paragraphs
+func (g *Generator) docComment(c comment) {
+	for _, lines := range [][]string{c.leading, c.trailing} {
+		if len(lines) > 0 {
+			g.P()
+			g.P(strings.Join(lines, "\n"))
+		}
+	}
+}
+
+// fieldLabel returns repeated, optional if field has presence or is message, or empty for singular field
+func fieldLabel(f *descriptor.FieldDescriptorProto) string {
+	if isRepeated(f) {
+		return "repeated"
+	}
+	if hasPresence(f) || *f.Type == descriptor.FieldDescriptorProto_TYPE_MESSAGE {
+		return "optional"
+	}
+	return ""
+}
+
+// docCell returns comment as one line markdown table cell
+func docCell(c comment) string {
+	s := strings.Join(append(append([]string{}, c.leading...), c.trailing...), " ")
+	return strings.Replace(s, "|", "\\|", -1)
+}
+
+// docCode returns code as one line markdown code span in table cell, empty if code is empty
+func docCode(code string) string {
+	if code == "" {
+		return ""
+	}
+	code = strings.Replace(code, "{XXX_INDENT}", "", -1)
+	code = strings.Replace(code, "\n", " ", -1)
+	return "`" + strings.Replace(code, "|", "\\|", -1) + "`"
+}
@@ -0,0 +1,326 @@
+// protoc-gen-sol by Celer Network Team
+
+package generator
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+func (g *Generator) generateEnum(e enumdes) {
+	name := enumName(e)
+	names := g.enumValueNames(e)
+	members := enumMembers(e)
+	var values []string
+	for _, i := range members {
+		values = append(values, names[i])
+	}
+	for i, v := range e.Value {
+		if !inInts(i, members) {
+			log.Print("warning: skip enum ", *e.Name, " value ", *v.Name, " as it's alias of another value with number ", *v.Number)
+		}
+	}
+	g.natspec(g.comments[(*descriptor.EnumDescriptorProto)(e)])
+	if !g.hasValueComments(e, members) {
+		g.P("enum ", name, " { ", strings.Join(values, ", "), " }\n")
+	} else {
+		// one value per line to keep its comments
+		g.P("enum ", name, " {")
+		g.In()
+		for j, i := range members {
+			sep := ","
+			if j == len(members)-1 {
+				sep = ""
+			}
+			if trailing := g.memberComment(g.comments[e.Value[i]]); trailing != "" {
+				g.P(names[i], sep, "   // ", trailing)
+			} else {
+				g.P(names[i], sep)
+			}
+		}
+		g.Out()
+		g.P("}\n")
+	}
+
+	g.generateFromNumber(e, name, names, members)
+	if g.hasToNumber(e) {
+		g.generateToNumber(e, name, names, members)
+	}
+	g.generateFromNumberOrUnknown(e, name, names, members)
+	if g.enumUtils {
+		g.generateEnumUtils(e, name, members)
+	}
+
+	g.P("// ", name, "[] decode function")
+	g.P("function ", name, "s(uint[] memory arr) internal pure returns (", name, "[] memory t) {")
+	g.In()
+	g.P("t = new ", name, "[](arr.length);")
+	g.P("for (uint i = 0; i < t.length; i++) { t[i] = ", enumConv(e, name, "arr[i]", enumMode(e)), "; }")
+	g.Out()
+	g.P("}\n")
+}
+
+// generateFromNumber generates function converting proto enum number to solidity enum,
+// needed when numbers have gaps, negatives, aliases or aren't in order, or unknown number must revert explicitly
+func (g *Generator) generateFromNumber(e enumdes, name string, names []string, members []int) {
+	g.P("// ", name, " from proto enum number, reverts if number isn't defined")
+	g.P("function ", fromNumberName(name), "(uint num) internal pure returns (", name, ") {")
+	g.In()
+	if isDenseEnum(e) {
+		g.P(g.solRequire("num", "<", strconv.Itoa(len(members)), "InvalidEnumValue(num)"))
+		g.P("return ", name, "(num);")
+	} else {
+		for _, i := range members {
+			g.P("if (num == ", wireNumber(*e.Value[i].Number), ") return ", name, ".", names[i], ";")
+		}
+		g.P(g.solRevert("InvalidEnumValue(num)"))
+	}
+	g.Out()
+	g.P("}\n")
+}
+
+// generateToNumber generates function converting solidity enum to proto enum number, eg. for encoding
+func (g *Generator) generateToNumber(e enumdes, name string, names []string, members []int) {
+	if isDenseEnum(e) {
+		g.P("// ", name, " to proto enum number, which is the same as enum ordinal as numbers are 0, 1, 2... in order")
+		g.P("function ", toNumberName(name), "(", name, " v) internal pure returns (uint) {")
+		g.In()
+		g.P("return uint(v);")
+		g.Out()
+		g.P("}\n")
+		return
+	}
+	g.P("// ", name, " to proto enum number, negative number is 64-bit two's complement as encoded on the wire")
+	g.P("function ", toNumberName(name), "(", name, " v) internal pure returns (uint) {")
+	g.In()
+	for j, i := range members {
+		if j == len(members)-1 {
+			g.P("return ", wireNumber(*e.Value[i].Number), ";")
+		} else {
+			g.P("if (v == ", name, ".", names[i], ") return ", wireNumber(*e.Value[i].Number), ";")
+		}
+	}
+	g.Out()
+	g.P("}\n")
+}
+
+// generateFromNumberOrUnknown generates function converting proto enum number to solidity enum,
+// unknown number is mapped to enum value of number 0, which proto3 requires to be the first one
+func (g *Generator) generateFromNumberOrUnknown(e enumdes, name string, names []string, members []int) {
+	g.P("// ", name, " from proto enum number, unknown number is ", name, ".", names[members[0]])
+	g.P("function ", orUnknownName(name), "(uint num) internal pure returns (", name, ") {")
+	g.In()
+	if isDenseEnum(e) {
+		g.P("if (num < ", len(members), ") return ", name, "(num);")
+	} else {
+		for _, i := range members[1:] {
+			g.P("if (num == ", wireNumber(*e.Value[i].Number), ") return ", name, ".", names[i], ";")
+		}
+	}
+	g.P("return ", name, ".", names[members[0]], ";")
+	g.Out()
+	g.P("}\n")
+}
+
+// hasValueComments returns whether any enum member of e has proto comment
+func (g *Generator) hasValueComments(e enumdes, members []int) bool {
+	for _, i := range members {
+		if _, ok := g.comments[e.Value[i]]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hasToNumber returns whether xxxToNumber is generated for enum e, for non dense enum or if enumutils is set.
+// xxxFromNumber and xxxFromNumberOrUnknown are always generated, as sol_open_enum_field of a field in
+// another package, which may be generated by another protoc run, can use them
+func (g *Generator) hasToNumber(e enumdes) bool {
+	return !isDenseEnum(e) || g.enumUtils
+}
+
+// generateEnumUtils generates isValidXxx, xxxCount and xxxToString functions of enum e named name
+func (g *Generator) generateEnumUtils(e enumdes, name string, members []int) {
+	fns := enumUtilNames(name)
+	g.P("// whether num is a proto enum number of ", name)
+	g.P("function ", fns[0], "(uint num) internal pure returns (bool) {")
+	g.In()
+	if isDenseEnum(e) {
+		g.P("return num < ", len(members), ";")
+	} else {
+		var conds []string
+		for _, i := range members {
+			conds = append(conds, "num == "+wireNumber(*e.Value[i].Number))
+		}
+		g.P("return ", strings.Join(conds, " || "), ";")
+	}
+	g.Out()
+	g.P("}\n")
+
+	g.P("// number of ", name, " members")
+	g.P("function ", fns[1], "() internal pure returns (uint) {")
+	g.In()
+	g.P("return ", len(members), ";")
+	g.Out()
+	g.P("}\n")
+
+	g.P("// proto enum value name of v, eg. for events and debug")
+	g.P("function ", fns[2], "(", name, " v) internal pure returns (string memory) {")
+	g.In()
+	names := g.enumValueNames(e)
+	for j, i := range members {
+		if j == len(members)-1 {
+			g.P(`return "`, *e.Value[i].Name, `";`)
+		} else {
+			g.P("if (v == ", name, ".", names[i], `) return "`, *e.Value[i].Name, `";`)
+		}
+	}
+	g.Out()
+	g.P("}\n")
+}
+
+// enumUtilNames returns names of isValidXxx, xxxCount and xxxToString functions of enum named name
+func enumUtilNames(name string) []string {
+	return []string{"isValid" + name, toLowerCamel(name) + "Count", toLowerCamel(name) + "ToString"}
+}
+
+// OpenEnumModes is a map of valid sol_open_enum values to its description
+var OpenEnumModes = map[string]string{
+	"revert":  "revert explicitly",
+	"unknown": "map to enum value of number 0",
+	"raw":     "map to enum value of number 0 and keep raw number in additional uint struct member",
+}
+
+// enumMode returns sol_open_enum option of e, "" if not set
+func enumMode(e enumdes) string {
+	if e.Options == nil {
+		return ""
+	}
+	return checkEnumMode(getStrOpt(e.Options, E_SolOpenEnum, "enum "+*e.Name), "enum "+*e.Name)
+}
+
+// fieldEnumMode returns sol_open_enum_field option of enum field f, or sol_open_enum of its enum e
+func fieldEnumMode(f *descriptor.FieldDescriptorProto, e enumdes) string {
+	if f.Options != nil {
+		if mode := checkEnumMode(getStrOpt(f.Options, E_SolOpenEnumField, "field "+*f.Name), "field "+*f.Name); mode != "" {
+			return mode
+		}
+	}
+	return enumMode(e)
+}
+
+func checkEnumMode(mode, what string) string {
+	if _, ok := OpenEnumModes[mode]; mode != "" && !ok {
+		Fail("invalid sol_open_enum", mode, "of", what, "expect revert, unknown or raw")
+	}
+	return mode
+}
+
+// enumFromNumber returns solidity expression converting proto enum number expr of enum field f
+// to solidity enum, eg. Status(expr) or statusFromNumber(expr), prefixed by library name if in other package
+func (g *Generator) enumFromNumber(f *descriptor.FieldDescriptorProto, expr string) string {
+	sym := g.lookup(*f.TypeName)
+	conv := enumConv(sym.enum, sym.name, expr, fieldEnumMode(f, sym.enum))
+	if sym.pkg != curPkg {
+		conv = sym.lib + "." + conv
+	}
+	return conv
+}
+
+// enumConv returns solidity expression converting proto enum number expr to enum e named name
+// by sol_open_enum mode
+func enumConv(e enumdes, name, expr, mode string) string {
+	switch {
+	case mode == "unknown" || mode == "raw":
+		return orUnknownName(name) + "(" + expr + ")"
+	case mode == "" && isDenseEnum(e):
+		return name + "(" + expr + ")" // ordinal is number
+	}
+	return fromNumberName(name) + "(" + expr + ")"
+}
+
+// enumMembers returns indexes of e.Value that are solidity enum members. alias values (allow_alias,
+// same number as an earlier value) are left out as solidity enum doesn't support alias
+func enumMembers(e enumdes) (idx []int) {
+	seen := make(map[int32]bool)
+	for i, v := range e.Value {
+		if seen[*v.Number] {
+			continue
+		}
+		seen[*v.Number] = true
+		idx = append(idx, i)
+	}
+	return
+}
+
+// isDenseEnum returns whether enum members are numbered 0..n-1 in order, so solidity enum ordinal equals proto number
+func isDenseEnum(e enumdes) bool {
+	for ord, i := range enumMembers(e) {
+		if int(*e.Value[i].Number) != ord {
+			return false
+		}
+	}
+	return true
+}
+
+// wireNumber returns decimal proto enum number as decoded by decVarint, negative int32 is encoded as 64-bit two's complement
+func wireNumber(n int32) string {
+	return strconv.FormatUint(uint64(int64(n)), 10)
+}
+
+func fromNumberName(enum string) string {
+	return toLowerCamel(enum) + "FromNumber"
+}
+
+func toNumberName(enum string) string {
+	return toLowerCamel(enum) + "ToNumber"
+}
+
+func orUnknownName(enum string) string {
+	return toLowerCamel(enum) + "FromNumberOrUnknown"
+}
+
+func inInts(n int, arr []int) bool {
+	for _, v := range arr {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
+
+// isRawEnum returns whether enum field f keeps raw number in additional struct member, by sol_open_enum raw mode
+func (g *Generator) isRawEnum(f *descriptor.FieldDescriptorProto) bool {
+	if f.GetType() != descriptor.FieldDescriptorProto_TYPE_ENUM {
+		return false
+	}
+	return fieldEnumMode(f, g.lookup(*f.TypeName).enum) == "raw"
+}
+
+// isOwnEnumMode returns whether enum field f has the same sol_open_enum mode as its enum
+func (g *Generator) isOwnEnumMode(f *descriptor.FieldDescriptorProto) bool {
+	e := g.lookup(*f.TypeName).enum
+	return fieldEnumMode(f, e) == enumMode(e)
+}
+
+// rawFieldName returns struct member name of raw number of enum field f, eg. statusRaw
+func (g *Generator) rawFieldName(f *descriptor.FieldDescriptorProto) string {
+	if g.naming == "snake" || g.naming == "proto" {
+		return g.fieldName(f) + "_raw"
+	}
+	return g.fieldName(f) + "Raw"
+}
+
+// rawSolType returns solidity type of raw number member of enum field f, with the same array suffix as the enum member
+func (g *Generator) rawSolType(f *descriptor.FieldDescriptorProto) string {
+	if !isRepeated(f) {
+		return "uint"
+	}
+	if n := g.getFixedLen(f); n > 0 {
+		return "uint[" + strconv.Itoa(n) + "]"
+	}
+	return "uint[]"
+}
@@ -9,6 +9,7 @@ package generator
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"fmt"
 	"log"
 	"os"
@@ -31,15 +32,39 @@ import (
 // ExtName is the extension name to google.protobuf.FieldOptions
 // its type must be string. Valid string values are keys in SolTypeMap
 // (we don't use enum to avoid having solidity knowledge in chain.proto)
+// it's defined in sol/options.proto, or can be declared in any .proto file of the request
 const ExtName = "soltype"
 
 // SolVer is the compatible solidity/solc version in pragma solidity
 const SolVer = ">=0.5.0;"
 
+// DefaultSolc is the default target solc version, can be changed by solc param
+const DefaultSolc = "0.5"
+
+// SolcPragmas is a map of supported target solc versions (solc param value) to pragma solidity
+// generated code and runtime library Pb use idioms of the target version, eg. for 0.8
+// custom errors, unchecked blocks and memory-safe assembly
+var SolcPragmas = map[string]string{
+	"0.5": SolVer,
+	"0.8": "^0.8.13;", // memory-safe assembly requires 0.8.13
+}
+
+// DefaultPbLib is the default runtime library name and generated library name prefix
+const DefaultPbLib = "Pb"
+
 // string const for proto wire types
 const WireVarint = "Varint"
 const WireLendel = "Bytes"
 
+// NamingStrategies is a map of supported naming param values to its description
+var NamingStrategies = map[string]string{
+	"default":    "proto field name with _x replaced by X, eg. var_name_one -> varNameOne, ownerID is kept",
+	"lowercamel": "lowerCamelCase of proto field name, eg. URL_value -> urlValue, ownerID -> ownerId",
+	"proto":      "same as proto field name",
+	"json":       "json_name of proto field, eg. the one set by [json_name = \"xxx\"]",
+	"snake":      "snake_case of proto field name, eg. fooBar -> foo_bar",
+}
+
 // PassTypeMap is a map of proto types with native solidity support (aka. same type keyword in proto and solidity)
 // to its wire type. keys are values from pbType2Str map below.
 // currently we only support 2 wire types: varint and length-delimited.
@@ -89,15 +114,52 @@ var curPkg string
 
 // Generator is the type whose methods generate the output, stored in the associated response structure.
 type Generator struct {
-	*bytes.Buffer                               // cache .P() output
-	Request       *plugin.CodeGeneratorRequest  // The input.
-	Response      *plugin.CodeGeneratorResponse // The output.
-	indent        string
-	extnum        int32           // assigned field number eg. 1001 for ExtName
-	importpb      bool            // whether to include library Pb in the generated .sol or import. if true, create import "Pb.sol" in header
-	onlymsgs      map[string]bool // msg names specified by user in arg as whitelist, if not empty, only generate msg if it's in the list
+	*bytes.Buffer                                // cache .P() output
+	Request        *plugin.CodeGeneratorRequest  // The input.
+	Response       *plugin.CodeGeneratorResponse // The output.
+	Version        string                        // protoc-gen-sol version, only used in provenance header
+	Commit         string                        // protoc-gen-sol commit, only used in provenance header
+	indent         string
+	soltypeExts    []*proto.ExtensionDesc  // all ExtName extensions declared in request files
+	importpb       bool                    // whether to include library Pb in the generated .sol or import. if true, create import "Pb.sol" in header
+	onlymsgs       map[string]bool         // msg names specified by user in arg as whitelist, if not empty, only generate msg if it's in the list
+	excludes       map[string]bool         // msg or enum names specified by user in exclude arg, not to generate
+	outputs        map[string]bool         // fully qualified names of messages and enums to generate, nil means all
+	solc           string                  // target solc version, key of SolcPragmas
+	license        string                  // SPDX license identifier, if empty, UNLICENSED is used for solc 0.6.8+
+	pragma         string                  // pragma solidity version range, overrides SolcPragmas[solc] if set
+	extraPragmas   []string                // additional pragmas eg. "abicoder v2"
+	extraImports   []string                // additional import paths
+	provenance     bool                    // whether to add generator version, input hash and params to header
+	pblib          string                  // runtime library name, also used as its file name when importpb
+	libPrefix      string                  // prefix of generated library name, eg. Pb for PbExample
+	libSuffix      string                  // suffix of generated library name
+	importPrefix   string                  // path prefix of generated imports eg. @celer/contracts/lib/, default ./
+	files          map[string]fdes         // all proto files in request, key is file name eg. celer/entity/entity.proto
+	symbols        map[string]*symbol      // all messages and enums in request, key is fully qualified name eg. .pkg.Msg
+	pkgLibs        map[string]string       // package to library name set by sol_library file option
+	naming         string                  // struct member naming strategy, key of NamingStrategies
+	stripEnumPfx   bool                    // whether to strip enum name prefix from enum value names eg. STATUS_OPEN -> OPEN
+	enumUtils      bool                    // whether to generate utility functions of enums eg. isValidXxx
+	timeNanos      bool                    // whether Timestamp and Duration are nanoseconds instead of seconds
+	skipDeprecated bool                    // whether to skip fields with deprecated = true option
+	anyHelpers     bool                    // whether to generate TYPE_URL_Xxx, isXxx and unpackXxx for google.protobuf.Any
+	typeURLPrefix  string                  // type url prefix of google.protobuf.Any
+	scoped         []scopedParam           // params scoped to a package or .proto file, by key@scope=value
+	doc            bool                    // whether to generate markdown reference of each package, see generateDoc
+	comments       map[interface{}]comment // proto comments of messages, fields, enums and enum values, see findComments
 }
 
+// scopedParam is a param only for a package or .proto file, eg. naming@mytest=snake
+type scopedParam struct {
+	scope, key, value string
+}
+
+// ScopedParams are params that can be scoped to a package or .proto file by key@scope=value,
+// others affect all files as they're used by cross package references or shared library Pb
+var ScopedParams = toSet(`msg exclude spdx pragma extrapragma extraimport provenance importprefix
+	naming stripenumprefix enumutils timenanos anyhelpers typeurlprefix doc`)
+
 // New creates a new generator and allocates the request and response protobufs.
 func New() *Generator {
 	g := new(Generator)
@@ -105,6 +167,15 @@ func New() *Generator {
 	g.Request = new(plugin.CodeGeneratorRequest)
 	g.Response = new(plugin.CodeGeneratorResponse)
 	g.onlymsgs = make(map[string]bool)
+	g.excludes = make(map[string]bool)
+	g.solc = DefaultSolc
+	g.pblib = DefaultPbLib
+	g.libPrefix = DefaultPbLib
+	g.importPrefix = "./"
+	g.naming = "default"
+	g.typeURLPrefix = "type.googleapis.com/"
+	g.files = make(map[string]fdes)
+	g.symbols = make(map[string]*symbol)
 	return g
 }
 
@@ -153,178 +224,533 @@ func (g *Generator) printAtom(v interface{}) {
 	}
 }
 
-// When we want to support multiple .proto and imports, need preprocess to get all definition relationships
+// Preprocess gets all definition relationships of all .proto files in request, so we can support
+// multiple .proto and imports. It must be called after ParseParams as solidity names depend on params
 func (g *Generator) Preprocess() {
-	// init text template?
+	for _, f := range g.Request.ProtoFile {
+		g.files[f.GetName()] = f
+	}
+	// soltype extension may be declared in any file, eg. imported sol/options.proto
+	g.soltypeExts = findSolTypeExts(g.Request.ProtoFile)
+	g.pkgLibs = findSolLibraries(g.Request.ProtoFile)
+	g.comments = findComments(g.Request.ProtoFile)
+	g.buildSymbols()
+	g.checkPackages()
+	g.checkScopes()
+	g.selectOutputs()
+	g.checkCycles()
 }
+
 func (g *Generator) ParseParams() {
-	// only support 2 args for now:
+	// supported args:
 	// msg=MsgA,msg=MsgB
 	// importpb=true/false (false is default), if true, will generate import "Pb.sol" instead of having library Pb in the generated .sol
-	// Note the param affects all .proto files
-	parameter := g.Request.GetParameter()
-	if len(parameter) == 0 {
+	// solc=0.5/0.8 (0.5 is default), target solc version of generated code
+	// spdx=MIT, SPDX license identifier in generated .sol header
+	// pragma=^0.8.20, pragma solidity version range, overrides the one from solc
+	// extrapragma=abicoder v2, additional pragma, can be specified multiple times
+	// extraimport=path/to/Foo.sol, additional import, can be specified multiple times
+	// provenance=true/false (false is default), if true, header has generator version, input file hash and params
+	// pblib=MyPb, runtime library name (Pb is default), also renames Pb.sol to MyPb.sol
+	// libprefix=Proto, libsuffix=Lib, generated library name is prefix + package + suffix (Pb and empty are default)
+	// importprefix=@celer/contracts/lib/, path prefix of generated imports (./ is default), to work with remappings
+	// naming=default/lowercamel/proto/json/snake (default is default), struct member naming strategy, see NamingStrategies
+	// stripenumprefix=true/false (false is default), if true, strip enum name prefix from enum values eg. STATUS_OPEN -> OPEN
+	// enumutils=true/false (false is default), if true, generate isValidXxx, xxxCount, xxxToString, xxxFromNumber and xxxToNumber for enums
+	// timenanos=true/false (false is default), if true, google.protobuf.Timestamp and Duration are uint nanoseconds instead of seconds
+	// anyhelpers=true/false (false is default), if true, generate TYPE_URL_Xxx, isXxx and unpackXxx
+	// skipdeprecated=true/false (false is default), if true, fields with deprecated = true option aren't in solidity struct
+	// typeurlprefix=example.com/, type url prefix of google.protobuf.Any (type.googleapis.com/ is default)
+	// doc=true/false (false is default), if true, also generate markdown reference Xxx.md of each generated Xxx.sol
+	// config=path/to/config.json, params in json config file, see loadConfig. other params override it
+	// Note the param affects all .proto files, unless it's scoped to a package or .proto file by key@scope=value,
+	// eg. msg@mytest=Msg3 or naming@b.proto=snake. only keys in ScopedParams can be scoped
+	// comma or backslash in value can be escaped by backslash, and bool param without value is true eg. importpb
+	params := splitParams(g.Request.GetParameter())
+	// config file first, so inline params override it
+	for _, p := range params {
+		if p.key == "config" {
+			g.loadConfig(p.value)
+		}
+	}
+	for _, p := range params {
+		if p.key != "config" {
+			g.addParam(p.key, p.value)
+		}
+	}
+}
+
+// addParam sets param key to value, or records it as scoped param if key is like naming@mytest
+func (g *Generator) addParam(key, value string) {
+	i := strings.Index(key, "@")
+	if i < 0 {
+		g.setParam(key, value)
 		return
 	}
-	for _, p := range strings.Split(parameter, ",") {
-		tmp := strings.Split(p, "=")
-		key, value := tmp[0], tmp[1]
-		switch key {
-		case "msg":
-			g.onlymsgs[value] = true
-		case "importpb":
-			if value == "true" {
-				g.importpb = true
+	key, scope := key[:i], key[i+1:]
+	if !ScopedParams[key] || scope == "" {
+		var scopable []string
+		for _, p := range ParamNames {
+			if ScopedParams[p] {
+				scopable = append(scopable, p)
 			}
-		default:
-			Fail("Unknown params ", key, value)
+		}
+		Fail("invalid param", key+"@"+scope, "only", strings.Join(scopable, ", "), "can be scoped")
+	}
+	if key != "msg" && key != "exclude" {
+		tmp := *g
+		tmp.setParam(key, value) // only validate value
+	}
+	g.scoped = append(g.scoped, scopedParam{scope, key, value})
+}
+
+// setParam sets param key to value, fails if key is unknown or value is invalid
+func (g *Generator) setParam(key, value string) {
+	switch key {
+	case "msg":
+		g.onlymsgs[value] = true
+	case "exclude":
+		g.excludes[value] = true
+	case "importpb":
+		g.importpb = parseBool(key, value)
+	case "solc":
+		if _, ok := SolcPragmas[value]; !ok {
+			Fail("unsupported solc version", value)
+		}
+		g.solc = value
+	case "spdx":
+		g.license = value
+	case "pragma":
+		g.pragma = nonEmpty(key, strings.TrimSuffix(value, ";")) + ";"
+	case "extrapragma":
+		g.extraPragmas = append(g.extraPragmas, nonEmpty(key, strings.TrimSuffix(value, ";")))
+	case "extraimport":
+		g.extraImports = append(g.extraImports, nonEmpty(key, value))
+	case "provenance":
+		g.provenance = parseBool(key, value)
+	case "pblib":
+		if !solIdentRe.MatchString(value) {
+			Fail("invalid pblib", value)
+		}
+		g.pblib = value
+	case "libprefix":
+		g.libPrefix = value
+	case "libsuffix":
+		g.libSuffix = value
+	case "importprefix":
+		g.importPrefix = value
+	case "naming":
+		if _, ok := NamingStrategies[value]; !ok {
+			Fail("unsupported naming", value, "expect default, lowercamel, proto, json or snake")
+		}
+		g.naming = value
+	case "stripenumprefix":
+		g.stripEnumPfx = parseBool(key, value)
+	case "enumutils":
+		g.enumUtils = parseBool(key, value)
+	case "timenanos":
+		g.timeNanos = parseBool(key, value)
+	case "anyhelpers":
+		g.anyHelpers = parseBool(key, value)
+	case "skipdeprecated":
+		g.skipDeprecated = parseBool(key, value)
+	case "typeurlprefix":
+		g.typeURLPrefix = value
+	case "doc":
+		g.doc = parseBool(key, value)
+	default:
+		Fail("unknown param", key+",", "valid params are", strings.Join(ParamNames, ", "))
+	}
+}
+
+// applyScopedParams sets params scoped to package or any of files, except msg and exclude which are
+// resolved by selectOutputs. it returns func to restore params for other packages
+func (g *Generator) applyScopedParams(files []fdes) (restore func()) {
+	saved := *g
+	for _, p := range g.scoped {
+		if p.key != "msg" && p.key != "exclude" && inScope(p.scope, files) {
+			g.setParam(p.key, p.value)
+		}
+	}
+	return func() { *g = saved }
+}
+
+// whether scope is the package or file name of any of files
+func inScope(scope string, files []fdes) bool {
+	for _, f := range files {
+		if d := (*descriptor.FileDescriptorProto)(f); scope == d.GetPackage() || scope == d.GetName() {
+			return true
 		}
 	}
+	return false
 }
 
 // GenerateAllFiles generates the output for all the files we're outputting.
+// .proto files of the same package are generated into one .sol file and library
 func (g *Generator) GenerateAllFiles() {
+	var pkgs []string               // packages to generate, in order of first appearance
+	pkgFiles := map[string][]fdes{} // package to its files to generate
 	for _, f := range g.Request.ProtoFile {
 		if !inArray(*f.Name, g.Request.FileToGenerate) {
 			// log.Println("Skip import file:", *f.Name)
-			// We could build fname to package mapping here
 			continue
 		}
+		pkg := f.GetPackage()
+		if _, ok := pkgFiles[pkg]; !ok {
+			pkgs = append(pkgs, pkg)
+		}
+		pkgFiles[pkg] = append(pkgFiles[pkg], f)
+	}
+	if len(pkgs) > 1 {
+		// if every generated .sol embeds library Pb, solc fails with duplicate declaration
+		// when a contract imports more than one of them. so always share one Pb.sol
+		if !g.importpb {
+			log.Print("warning: importpb is set to true as .proto files of packages ", strings.Join(pkgs, ", "), " are given, generated .sol files import ", g.pblib, ".sol")
+			g.importpb = true
+		}
+	}
+	for _, pkg := range pkgs {
+		files := pkgFiles[pkg]
+		// sort by file name so output doesn't depend on order of files in protoc command
+		sort.Slice(files, func(i, j int) bool { return *files[i].Name < *files[j].Name })
 		g.Reset() // clear buffer
-		g.generate(f)
-		outfn := getSolFile(f.GetPackage()) // file name for generated .sol file
+		restore := g.applyScopedParams(files)
+		g.generate(files)
+		outfn := g.getSolFile(pkg) // file name for generated .sol file
 		g.Response.File = append(g.Response.File, &plugin.CodeGeneratorResponse_File{
 			Name:    proto.String(outfn),
 			Content: proto.String(g.String()),
 		})
+		if g.doc {
+			g.Reset()
+			g.generateDoc(files)
+			g.Response.File = append(g.Response.File, &plugin.CodeGeneratorResponse_File{
+				Name:    proto.String(strings.TrimSuffix(outfn, ".sol") + ".md"),
+				Content: proto.String(g.String()),
+			})
+		}
+		restore()
 	}
 	if g.importpb {
+		g.Reset()
+		g.generateSpdx()
+		g.generateProvenance(nil)
+		g.generatePragmas()
+		g.WriteString(g.protoSol())
 		g.Response.File = append(g.Response.File, &plugin.CodeGeneratorResponse_File{
-			Name:    proto.String("Pb.sol"),
-			Content: proto.String("pragma solidity " + SolVer + "\n" + ProtoSol),
+			Name:    proto.String(g.pblib + ".sol"),
+			Content: proto.String(g.String()),
 		})
 	}
 }
 
 // Fill the response protocol buffer with the generated output for all the files we're
-// supposed to generate.
-func (g *Generator) generate(f fdes) {
-	if *f.Syntax != "proto3" {
-		Fail("Only support proto3")
-	}
-	// find ExtName number if it's defined, -1 if not
-	g.extnum = getExtNum(f)
-	curPkg = *f.Package
-	g.generateHeader(f)
+// supposed to generate. files must have the same package.
+func (g *Generator) generate(files []fdes) {
+	for _, f := range files {
+		if *f.Syntax != "proto3" {
+			Fail("Only support proto3", *f.Name)
+		}
+	}
+	curPkg = *files[0].Package
+	g.checkNames(files)
+	g.generateHeader(files)
 	g.In()
-	g.P("using Pb for Pb.Buffer;  // so we can call Pb funcs on Buffer obj\n")
+	g.P("using ", g.pblib, " for ", g.pblib, ".Buffer;  // so we can call ", g.pblib, " funcs on Buffer obj\n")
 
 	// go over all top level enums
-	for _, enum := range f.EnumType {
-		g.generateEnum(enum)
+	for _, f := range files {
+		for _, enum := range f.EnumType {
+			if g.shouldOutput(*f.Package, *enum.Name) {
+				g.generateEnum(enum)
+			}
+		}
 	}
 
 	// go over all top level messages
-	for _, msg := range f.MessageType {
-		if g.shouldOutput(*msg.Name) {
-			g.generateMsg(msg)
+	for _, f := range files {
+		for _, msg := range f.MessageType {
+			if g.shouldOutput(*f.Package, *msg.Name) {
+				g.generateMsg(msg)
+				if g.anyHelpers {
+					g.generateAnyHelpers(msg)
+				}
+			}
 		}
 	}
 	g.Out()
 	g.P("}") // close library
-	if !g.importpb {
-		g.P(ProtoSol)
+	if !g.importpb && len(g.getSolDeps(files)) == 0 {
+		// if files import other generated .sol, library Pb is already declared in or imported by them
+		g.P(g.protoSol())
 	}
 }
 
-// Generate the header, including package definition
-func (g *Generator) generateHeader(f fdes) {
-	g.P("// Code generated by protoc-gen-sol. DO NOT EDIT.")
-	g.P("// source: ", f.Name)
-	g.P("pragma solidity ", SolVer)
-	if g.importpb {
-		g.P(`import "./Pb.sol";`)
+// checkNames fails if generated identifiers collide in library, struct or enum scope
+func (g *Generator) checkNames(files []fdes) {
+	lib := g.getSolLib(*files[0].Package)
+	libScope := newNameScope("library " + lib)
+	libScope.add(lib, "library "+lib)
+	libScope.add(g.pblib, "runtime library "+g.pblib)
+	for _, dep := range g.getSolDeps(files) {
+		name := strings.TrimSuffix(dep, ".sol")
+		libScope.add(name, "imported library "+name)
 	}
-	for _, i := range f.Dependency {
-		if i != "google/protobuf/descriptor.proto" {
-			// require proto file name and package name are same
-			g.P(`import "./`, getSolFile(strings.TrimSuffix(i, ".proto")), `";`)
+	for _, f := range files {
+		for _, e := range f.EnumType {
+			if !g.shouldOutput(*f.Package, *e.Name) {
+				continue
+			}
+			name := enumName(e)
+			libScope.add(name, "enum "+*e.Name)
+			libScope.add(name+"s", "enum "+*e.Name+" array decode function")
+			libScope.add(fromNumberName(name), "enum "+*e.Name+" number conversion function")
+			if g.hasToNumber(e) {
+				libScope.add(toNumberName(name), "enum "+*e.Name+" number conversion function")
+			}
+			libScope.add(orUnknownName(name), "enum "+*e.Name+" number conversion function")
+			if g.enumUtils {
+				for _, fn := range enumUtilNames(name) {
+					libScope.add(fn, "enum "+*e.Name+" utility function")
+				}
+			}
+			valScope := newNameScope("enum " + *e.Name)
+			names := g.enumValueNames(e)
+			for _, i := range enumMembers(e) {
+				valScope.add(names[i], "enum value "+*e.Value[i].Name)
+			}
+		}
+		for _, m := range f.MessageType {
+			if !g.shouldOutput(*f.Package, *m.Name) {
+				continue
+			}
+			name := msgName(m)
+			libScope.add(name, "message "+*m.Name)
+			libScope.add(getDecFname(name), "message "+*m.Name+" decode function")
+			if g.anyHelpers {
+				for _, n := range anyHelperNames(name) {
+					libScope.add(n, "message "+*m.Name+" Any helper")
+				}
+			}
+			fieldScope := newNameScope("message " + *m.Name)
+			for _, f := range g.solFields(m) {
+				fieldScope.add(g.fieldName(f), "field "+*f.Name)
+				if g.isRawEnum(f) {
+					fieldScope.add(g.rawFieldName(f), "raw number of field "+*f.Name)
+				}
+				if hasPresence(f) {
+					fieldScope.add(g.presenceName(f), "presence of field "+*f.Name)
+				}
+			}
 		}
 	}
-	g.P()
-	g.P("library ", getSolLib(*f.Package), " {")
 }
 
-func (g *Generator) generateEnum(e enumdes) {
-	s := "enum " + *(e.Name) + " { "
-	// assume enum definition is perfect (in order and no gaps)
-	// TODO(enum): robust for disorders and gaps
-	var values []string
-	for i, v := range e.Value {
-		values = append(values, *(v.Name))
-		if int(*v.Number) != i {
-			Fail("enum values must start from 0 and no skip numbers")
+// protoSol returns runtime library for target solc version, named as pblib
+func (g *Generator) protoSol() string {
+	lib := ProtoSol
+	if g.solc == "0.8" {
+		lib = ProtoSol08
+	}
+	return strings.Replace(lib, "library Pb {", "library "+g.pblib+" {", 1)
+}
+
+// generateSpdx outputs SPDX license comment line if spdx param is set.
+// solc 0.6.8+ warns if it's missing so we use UNLICENSED by default for 0.8
+func (g *Generator) generateSpdx() {
+	license := g.license
+	if license == "" && g.solc != "0.5" {
+		license = "UNLICENSED"
+	}
+	if license != "" {
+		g.P("// SPDX-License-Identifier: ", license)
+	}
+}
+
+// generateProvenance outputs generator version, commit, hash of input files and params if provenance is set
+// files is empty for runtime library file Pb.sol
+func (g *Generator) generateProvenance(files []fdes) {
+	if !g.provenance {
+		return
+	}
+	g.P("// protoc-gen-sol version: ", g.Version, " commit: ", g.Commit)
+	for _, f := range files {
+		// protoc doesn't pass raw .proto content to plugin, hash serialized file descriptor instead
+		raw, err := proto.Marshal((*descriptor.FileDescriptorProto)(f))
+		if err != nil {
+			Error(err, "failed to marshal", *f.Name)
 		}
+		g.P(fmt.Sprintf("// %s descriptor sha256: %x", *f.Name, sha256.Sum256(raw)))
 	}
-	s = s + strings.Join(values, ", ") + " }\n"
-	g.P(s)
+	g.P("// params: ", g.Request.GetParameter())
+}
 
-	g.P("// ", e.Name, "[] decode function")
-	g.P("function ", e.Name, "s(uint[] memory arr) internal pure returns (", e.Name, "[] memory t) {")
-	g.In()
-	g.P("t = new ", e.Name, "[](arr.length);")
-	g.P("for (uint i = 0; i < t.length; i++) { t[i] = ", e.Name, "(arr[i]); }")
-	g.Out()
-	g.P("}\n")
+// generatePragmas outputs pragma solidity and extra pragmas
+func (g *Generator) generatePragmas() {
+	if g.pragma != "" {
+		g.P("pragma solidity ", g.pragma)
+	} else {
+		g.P("pragma solidity ", SolcPragmas[g.solc])
+	}
+	for _, p := range g.extraPragmas {
+		g.P("pragma ", p, ";")
+	}
+}
+
+// solRequire returns solidity code to revert if lhs op rhs is false.
+// for solc 0.8 it uses custom error errcall defined in runtime library, eg. WrongArrayLength(1, cnts[1])
+func (g *Generator) solRequire(lhs, op, rhs, errcall string) string {
+	if g.solc == "0.5" {
+		return fmt.Sprintf("require(%s %s %s);", lhs, op, rhs)
+	}
+	negOp := map[string]string{"==": "!=", "!=": "==", "<=": ">", ">=": "<", "<": ">=", ">": "<="}
+	return fmt.Sprintf("if (%s %s %s) revert %s.%s;", lhs, negOp[op], rhs, g.pblib, errcall)
+}
+
+// solRevert returns solidity code to revert unconditionally, errcall is custom error for solc 0.8
+func (g *Generator) solRevert(errcall string) string {
+	if g.solc == "0.5" {
+		return "revert();"
+	}
+	return fmt.Sprintf("revert %s.%s;", g.pblib, errcall)
+}
+
+// Generate the header, including package definition
+func (g *Generator) generateHeader(files []fdes) {
+	var names []string
+	for _, f := range files {
+		names = append(names, *f.Name)
+	}
+	g.generateSpdx()
+	g.P("// Code generated by protoc-gen-sol. DO NOT EDIT.")
+	g.P("// source: ", strings.Join(names, ", "))
+	g.generateProvenance(files)
+	g.generatePragmas()
+	if g.importpb {
+		g.P(`import "`, g.importPrefix, g.pblib, `.sol";`)
+	}
+	for _, i := range g.extraImports {
+		g.P(`import "`, i, `";`)
+	}
+	for _, dep := range g.getSolDeps(files) {
+		g.P(`import "`, g.importPrefix, dep, `";`)
+	}
+	g.P()
+	g.P("library ", g.getSolLib(*files[0].Package), " {")
 }
 
 func (g *Generator) generateMsg(m msgdes) {
 	// map from tag(field number) to its decoder solidity code string
 	tag2dec := make(map[int]string)
 
-	g.P("struct ", m.Name, " {")
+	name := msgName(m)
+	g.natspec(g.comments[(*descriptor.DescriptorProto)(m)])
+	g.P("struct ", name, " {")
 	g.In()
 	// because solidity doesn't support dynamic sized memory array
 	// we need to count tag(field number) occurrences for repeated bytes or messages
 	// then new the correct size array.
 	// repeated uint doesn't need this because it's packed
 	needNew := []string{"uint[] memory cnts = buf.cntTags({MAX_TAG});"}
+	// fixed-size arrays don't need new, but cnts is still used to check element count matches array size
+	var needCheck []string
+	needTags := false // whether we need buf.cntTags or only a zeroed counter array
 	// go over fields and put decode string into tag2dec
 	for _, f := range m.Field {
-		t := getSolType(f, g.extnum)
-		g.P(t, " ", toSolNaming(f.Name), ";", "   // tag: ", f.Number)
-		tag2dec[int(*f.Number)] = getSolDecodeStr(f, t)
-		if isRepeated(f) && (getWiretype(*f.Type) == WireLendel) {
-			needNew = append(needNew, fmt.Sprintf("m.%s = new %s(cnts[%d]);", toSolNaming(f.Name), t, *f.Number))
+		if g.isSkipped(f) {
+			log.Print("skip field ", *m.Name, ".", *f.Name, ", it's decoded as unknown field")
+		}
+	}
+	fields := g.solFields(m)
+	for _, f := range fields {
+		t := g.getSolType(f)
+		cmt := g.fieldComment(f)
+		if trailing := g.memberComment(g.comments[f]); trailing != "" {
+			cmt += " - " + trailing
+		}
+		g.P(t, " ", g.fieldName(f), ";", "   ", cmt)
+		if g.isRawEnum(f) {
+			g.P(g.rawSolType(f), " ", g.rawFieldName(f), ";", "   // tag: ", f.Number, " raw enum number")
+		}
+		if hasPresence(f) {
+			g.P("bool ", g.presenceName(f), ";", "   // tag: ", f.Number, " whether it's set")
+		}
+		tag2dec[int(*f.Number)] = g.getSolDecodeStr(f, t)
+		if n := g.getFixedLen(f); n > 0 {
+			if getWiretype(*f.Type) == WireLendel {
+				// each element has its own tag so cntTags is the exact element count
+				needTags = true
+				needNew = append(needNew, g.fixedLenCheck(f, n))
+			} else {
+				// packed, element count is only known after decoding
+				needCheck = append(needCheck, g.fixedLenCheck(f, n))
+			}
+			needNew = append(needNew, fmt.Sprintf("cnts[%d] = 0;  // reset counter for later use", *f.Number))
+		} else if isRepeated(f) && (getWiretype(*f.Type) == WireLendel) {
+			needTags = true
+			needNew = append(needNew, fmt.Sprintf("m.%s = new %s(cnts[%d]);", g.fieldName(f), t, *f.Number))
 			needNew = append(needNew, fmt.Sprintf("cnts[%d] = 0;  // reset counter for later use", *f.Number))
 		}
 	}
+	if len(fields) == 0 {
+		// solidity doesn't allow empty struct. the flag tells whether the message is present, eg. as marker field
+		g.P("bool present;   // placeholder, set true by decoder")
+	}
 	g.Out()
-	g.P("} ", "// end struct ", m.Name, "\n")
+	g.P("} ", "// end struct ", name, "\n")
 
 	// sorted tags
 	stags := sortedTags(tag2dec)
 	// generate decoder. we make decode function name the same as message to unify type cast
 	// we use m for return struct name, saves us one g.P
-	g.P("function ", getDecFname(*m.Name), "(bytes memory raw) internal pure returns (", m.Name, " memory m) {")
+	g.P("/// @notice decode ", *m.Name, " message from protobuf encoded raw bytes")
+	g.P("function ", getDecFname(name), "(bytes memory raw) internal pure returns (", name, " memory m) {")
 	g.In()
-	g.P("Pb.Buffer memory buf = Pb.fromBytes(raw);\n")
+	g.P(g.pblib, ".Buffer memory buf = ", g.pblib, ".fromBytes(raw);\n")
 	if len(needNew) > 1 { // some fields need to new
-		g.P(strings.Replace(needNew[0], "{MAX_TAG}", strconv.Itoa(stags[len(stags)-1]), 1)) // replace placeholder w/ actual max tag number
+		if !needTags {
+			// only packed fixed-size arrays, no need to count tags, just allocate zeroed counters
+			needNew = []string{"uint[] memory cnts = new uint[]({MAX_TAG} + 1);"}
+		}
+		// replace placeholder w/ actual max tag number, including skipped fields as cntTags counts all tags of the message
+		maxTag := stags[len(stags)-1]
+		for _, f := range m.Field {
+			if int(*f.Number) > maxTag {
+				maxTag = int(*f.Number)
+			}
+		}
+		g.P(strings.Replace(needNew[0], "{MAX_TAG}", strconv.Itoa(maxTag), 1))
 		for _, s := range needNew[1:] {
 			g.P(s)
 		}
 		g.P()
 	}
+	if len(stags) == 0 {
+		// no fields, skip everything received
+		g.P("m.present = true;")
+		g.P(g.pblib, ".WireType wire;")
+		g.P("while (buf.hasMore()) {")
+		g.In()
+		g.P("(, wire) = buf.decKey();")
+		g.P("buf.skipValue(wire);  // skip value as message has no fields")
+		g.Out()
+		g.P("}")
+		g.Out()
+		g.P("} ", "// end decoder ", name, "\n")
+		return
+	}
 	g.P("uint tag;")
-	g.P("Pb.WireType wire;")
+	g.P(g.pblib, ".WireType wire;")
 	g.P("while (buf.hasMore()) {")
 	g.In()
 	g.P("(tag, wire) = buf.decKey();")
-	g.P("if (false) {} // solidity has no switch/case")
 	// have to use if clause because solidity doesn't support switch
-	for _, k := range stags {
-		g.P("else if (tag == ", k, ") {")
+	for i, k := range stags {
+		if i == 0 {
+			g.P("if (tag == ", k, ") {")
+		} else {
+			g.P("else if (tag == ", k, ") {")
+		}
 		g.In()
 		g.P(strings.Replace(tag2dec[k], "{XXX_INDENT}", g.indent, -1))
 		g.Out()
@@ -333,20 +759,56 @@ func (g *Generator) generateMsg(m msgdes) {
 	g.P("else { buf.skipValue(wire); } // skip value of unknown tag")
 	g.Out()
 	g.P("}")
+	for _, s := range needCheck {
+		g.P(s)
+	}
 	g.Out()
-	g.P("} ", "// end decoder ", m.Name, "\n")
+	g.P("} ", "// end decoder ", name, "\n")
 	// TODO(oneof): check m.OneofDecl and generate struct members and funcs
 }
-func (g *Generator) shouldOutput(msgname string) bool {
-	if len(g.onlymsgs) == 0 {
+
+// fixedLenCheck returns solidity code to check decoded element count of fixed-size array field is n
+func (g *Generator) fixedLenCheck(f *descriptor.FieldDescriptorProto, n int) string {
+	cnt := fmt.Sprintf("cnts[%d]", *f.Number)
+	return g.solRequire(cnt, "==", strconv.Itoa(n), fmt.Sprintf("WrongArrayLength(%d, %s)", *f.Number, cnt)) +
+		fmt.Sprintf("  // %s must have exactly %d elements", g.fieldName(f), n)
+}
+
+// shouldOutput returns whether top level message or enum name of proto package pkg is generated
+func (g *Generator) shouldOutput(pkg, name string) bool {
+	if g.outputs == nil {
 		return true
 	}
-	_, ok := g.onlymsgs[msgname]
-	return ok
+	return g.outputs["."+pkg+"."+name]
 }
 
 // helper functions below.
 
+// getSolDeps returns generated .sol files that need to be imported for types used by messages in files
+// types are resolved by symbol table, so it works for import public or proto file name doesn't match package
+func (g *Generator) getSolDeps(files []fdes) (deps []string) {
+	imported := make(map[string]bool)
+	for _, f := range files {
+		for _, msg := range f.MessageType {
+			if !g.shouldOutput(*f.Package, *msg.Name) {
+				continue
+			}
+			for _, field := range g.solFields(msg) {
+				if field.TypeName == nil || isWkt(field) {
+					continue
+				}
+				sym := g.lookup(*field.TypeName)
+				if sym.pkg == *f.Package || imported[sym.file] {
+					continue
+				}
+				imported[sym.file] = true
+				deps = append(deps, sym.file)
+			}
+		}
+	}
+	return
+}
+
 // whether s is in arr
 func inArray(s string, arr []string) bool {
 	for _, v := range arr {
@@ -358,49 +820,113 @@ func inArray(s string, arr []string) bool {
 }
 
 // return solidity code to decode this field
-func getSolDecodeStr(field *descriptor.FieldDescriptorProto, soltype string) (code string) {
+func (g *Generator) getSolDecodeStr(field *descriptor.FieldDescriptorProto, soltype string) (code string) {
 	// soltype could be uint256 or another message name
-	soltype = strings.TrimSuffix(soltype, "[]") // remove [] for array, no-op if doesn't have it
+	soltype, fixedLen := splitArrayType(soltype) // remove [] or [N] for array, no-op if doesn't have it
 	wire := getWiretype(*field.Type)
 	// in proto3, repeated varints are default packed, we don't support option packed=false for now
 	isPacked := isRepeated(field) && wire == WireVarint
+	if isPacked && fixedLen > 0 {
+		// fixed-size array can't be assigned from uint[], copy elements one by one.
+		// cnts[tag] tracks how many elements are decoded so far as packed field may appear multiple times
+		code = "uint[] memory tmp = buf.decPacked();\n"
+		cnt := fmt.Sprintf("cnts[%d] + tmp.length", *field.Number)
+		code += "{XXX_INDENT}" + g.solRequire(cnt, "<=", strconv.Itoa(fixedLen), fmt.Sprintf("WrongArrayLength(%d, %s)", *field.Number, cnt)) + "  // too many elements\n"
+		raw := ""
+		if g.isRawEnum(field) {
+			raw = fmt.Sprintf(" m.%s[cnts[%d] + i] = tmp[i];", g.rawFieldName(field), *field.Number)
+		}
+		code += fmt.Sprintf("{XXX_INDENT}for (uint i = 0; i < tmp.length; i++) { m.%s[cnts[%d] + i] = %s;%s }\n",
+			g.fieldName(field), *field.Number, g.getVarintConv(field, soltype, "tmp[i]"), raw)
+		code += fmt.Sprintf("{XXX_INDENT}cnts[%d] += tmp.length;", *field.Number)
+		return
+	}
 	if isPacked {
 		// buf.decPacked return uint[], use Pb.uintXXs to convert to uintXX[]
 		if soltype == "uint" {
-			code = fmt.Sprintf("m.%s = buf.decPacked();", toSolNaming(field.Name))
+			code = fmt.Sprintf("m.%s = buf.decPacked();", g.fieldName(field))
+		} else if *field.Type == descriptor.FieldDescriptorProto_TYPE_ENUM && g.isRawEnum(field) {
+			code = fmt.Sprintf("m.%s = buf.decPacked();\n", g.rawFieldName(field))
+			code += fmt.Sprintf("{XXX_INDENT}m.%s = new %s[](m.%s.length);\n", g.fieldName(field), soltype, g.rawFieldName(field))
+			code += fmt.Sprintf("{XXX_INDENT}for (uint i = 0; i < m.%s.length; i++) { m.%s[i] = %s; }",
+				g.rawFieldName(field), g.fieldName(field), g.getVarintConv(field, soltype, "m."+g.rawFieldName(field)+"[i]"))
+		} else if *field.Type == descriptor.FieldDescriptorProto_TYPE_ENUM && g.isOwnEnumMode(field) {
+			code = fmt.Sprintf("m.%s = %ss(buf.decPacked());", g.fieldName(field), soltype)
 		} else if *field.Type == descriptor.FieldDescriptorProto_TYPE_ENUM {
-			code = fmt.Sprintf("m.%s = %ss(buf.decPacked());", toSolNaming(field.Name), soltype)
+			// sol_open_enum_field differs from its enum, Xxxs conversion of the enum can't be used
+			code = "uint[] memory tmp = buf.decPacked();\n"
+			code += fmt.Sprintf("{XXX_INDENT}m.%s = new %s[](tmp.length);\n", g.fieldName(field), soltype)
+			code += fmt.Sprintf("{XXX_INDENT}for (uint i = 0; i < tmp.length; i++) { m.%s[i] = %s; }",
+				g.fieldName(field), g.getVarintConv(field, soltype, "tmp[i]"))
 		} else {
-			code = fmt.Sprintf("m.%s = Pb.%ss(buf.decPacked());", toSolNaming(field.Name), soltype)
+			code = fmt.Sprintf("m.%s = %s.%ss(buf.decPacked());", g.fieldName(field), g.pblib, soltype)
 		}
 		return
 	}
 	// additional optimization can be done to only cast if soltype != decXXX native types
-	if *field.Type == descriptor.FieldDescriptorProto_TYPE_MESSAGE {
+	if isWkt(field) {
+		decfun := g.getWktConv(field, "buf.decBytes()")
+		if isRepeated(field) {
+			code = fmt.Sprintf("m.%s[cnts[%d]] = %s;\n", g.fieldName(field), *field.Number, decfun)
+			code += fmt.Sprintf("{XXX_INDENT}cnts[%d]++;", *field.Number)
+		} else {
+			code = fmt.Sprintf("m.%s = %s;", g.fieldName(field), decfun)
+		}
+		if hasPresence(field) {
+			code += fmt.Sprintf("\n{XXX_INDENT}m.%s = true;", g.presenceName(field))
+		}
+		return
+	}
+	if *field.Type == descriptor.FieldDescriptorProto_TYPE_MESSAGE && soltype == "bytes" {
+		soltype = "" // keep raw bytes of message, no decoder
+	} else if *field.Type == descriptor.FieldDescriptorProto_TYPE_MESSAGE {
 		soltype = getDecFname(soltype) // use decMsg for msg decoder
 	} else if *field.Type == descriptor.FieldDescriptorProto_TYPE_ENUM {
-		// ENUM only needs an explicit conversion, so it doesn't need to change soltype at all
+		// ENUM only needs an explicit conversion or mapping from proto number, done below
 		// Example: m.enum = EnumName(buf.decVarint());
 	} else {
 		_, ok := SolTypeMap[soltype]
 		if soltype == "address payable" {
-			soltype = "Pb._addressPayable" // for address payable
+			soltype = g.pblib + "._addressPayable" // for address payable
 		} else if (ok && wire == WireLendel) || soltype == "bool" {
-			soltype = "Pb._" + soltype // if sol type like uint256, need special conv func in Pb library
+			soltype = g.pblib + "._" + soltype // if sol type like uint256, need special conv func in Pb library
 		}
 	}
 
 	decfun := fmt.Sprintf("%s(buf.dec%s())", soltype, wire)
+	if soltype == "" {
+		decfun = fmt.Sprintf("buf.dec%s()", wire)
+	} else if *field.Type == descriptor.FieldDescriptorProto_TYPE_ENUM && g.isRawEnum(field) {
+		// keep raw number, then convert it
+		code = fmt.Sprintf("m.%s = buf.decVarint();\n", g.rawFieldName(field))
+		code += fmt.Sprintf("{XXX_INDENT}m.%s = %s;", g.fieldName(field), g.getVarintConv(field, soltype, "m."+g.rawFieldName(field)))
+		return
+	} else if *field.Type == descriptor.FieldDescriptorProto_TYPE_ENUM {
+		decfun = g.getVarintConv(field, soltype, "buf.decVarint()")
+	}
 
 	if isRepeated(field) {
-		code = fmt.Sprintf("m.%s[cnts[%d]] = %s;\n", toSolNaming(field.Name), *field.Number, decfun)
+		code = fmt.Sprintf("m.%s[cnts[%d]] = %s;\n", g.fieldName(field), *field.Number, decfun)
 		code += fmt.Sprintf("{XXX_INDENT}cnts[%d]++;", *field.Number)
 	} else {
-		code = fmt.Sprintf("m.%s = %s;", toSolNaming(field.Name), decfun)
+		code = fmt.Sprintf("m.%s = %s;", g.fieldName(field), decfun)
 	}
 	return
 }
 
+// return solidity expression converting a decoded varint expr (uint) to soltype
+func (g *Generator) getVarintConv(field *descriptor.FieldDescriptorProto, soltype, expr string) string {
+	if soltype == "uint" {
+		return expr
+	} else if soltype == "bool" {
+		return expr + " != 0"
+	} else if *field.Type == descriptor.FieldDescriptorProto_TYPE_ENUM {
+		return g.enumFromNumber(field, expr)
+	}
+	// uintXX are explicit conversions
+	return soltype + "(" + expr + ")"
+}
+
 // wiretype string, WireVarint or WireLendel
 // packed ints is handled by getPbDecFunc
 func getWiretype(fieldtype descriptor.FieldDescriptorProto_Type) string {
@@ -423,26 +949,44 @@ func getWiretype(fieldtype descriptor.FieldDescriptorProto_Type) string {
 
 // getSolType return solidity type as string
 // if soltype option is set, uses that, otherwise use field.Type
-// will also append [] if field is repeated
-func getSolType(field *descriptor.FieldDescriptorProto, extnum int32) (s string) {
+// will also append [] if field is repeated, or [N] if soltype option has fixed-size array suffix
+func (g *Generator) getSolType(field *descriptor.FieldDescriptorProto) (s string) {
+	opt, fixedLen := splitArrayType(g.getSolTypeOpt(field))
+	if fixedLen > 0 && !isRepeated(field) {
+		Fail("fixed-size array soltype requires repeated field", *field.Name)
+	}
 	// use solidity array for repeated field
 	if isRepeated(field) {
-		defer func() { s += "[]" }()
+		if fixedLen > 0 {
+			defer func() { s += "[" + strconv.Itoa(fixedLen) + "]" }()
+		} else {
+			defer func() { s += "[]" }()
+		}
 	}
 	isMessage := *field.Type == descriptor.FieldDescriptorProto_TYPE_MESSAGE
 	isEnum := *field.Type == descriptor.FieldDescriptorProto_TYPE_ENUM
 	if isMessage || isEnum {
-		// TypeName is fullyqualified name eg. .pkg1.pkg2.mymsg.submsg
-		// only support 2 layers like .pkg.msg or .pkg.enum now
-		// Need to update this to properly support proto import/namespace/nested definition
-		fqn := strings.Split(*field.TypeName, ".")
-		if len(fqn) != 3 {
-			Fail("unsupported name hierarchy", *field.TypeName, "Expect .pkg.msg")
-		}
-		if fqn[1] == curPkg { // within same package, use only msg/enum name
-			s = fqn[2]
+		// for message and enum, soltype can only be fixed-size array suffix like "[2]",
+		// or bytes for message to keep raw bytes eg. for recursive message, decode it later by decoder of the message
+		if isMessage && opt == "bytes" {
+			return opt
+		}
+		if isWkt(field) {
+			if opt != "" {
+				Fail("incompatible types", *field.TypeName, opt, "only [N] is allowed as soltype of well-known type")
+			}
+			return g.getWktType(field)
+		}
+		if opt != "" {
+			Fail("incompatible types", *field.TypeName, opt, "only [N] or bytes is allowed as soltype of message, only [N] of enum")
+		}
+		// TypeName is fullyqualified name eg. .pkg1.pkg2.mymsg
+		// nested definition like .pkg.mymsg.submsg isn't supported
+		sym := g.lookup(*field.TypeName)
+		if sym.pkg == curPkg { // within same package, use only msg/enum name
+			s = sym.name
 		} else {
-			s = getSolLib(fqn[1]) + "." + fqn[2]
+			s = sym.lib + "." + sym.name
 		}
 		return
 	}
@@ -451,30 +995,79 @@ func getSolType(field *descriptor.FieldDescriptorProto, extnum int32) (s string)
 	if !ok {
 		Fail("unsupported proto type", (*field.Type).String())
 	}
+	if opt == "" || opt == s { // no soltype or same as proto type eg. "uint64[2]" for repeated uint64
+		return
+	}
+	if s != SolTypeMap[opt] { // s must match opt requirement
+		Fail("incompatible types", s, opt)
+	}
+	return opt
+}
 
-	if field.Options != nil && extnum != -1 {
-		v, err := proto.GetExtension(field.Options, &proto.ExtensionDesc{Field: extnum})
-		if err == nil {
-			b := proto.NewBuffer(v.([]byte))
-			b.DecodeVarint() // tag
-			s2, err := b.DecodeStringBytes()
-			if err == nil && s == SolTypeMap[s2] { // s matches s2 requirement
-				s = s2
-			} else {
-				Fail("incompatible types", s, s2)
-			}
+// getSolTypeOpt returns the soltype option string of field, "" if not set
+func (g *Generator) getSolTypeOpt(field *descriptor.FieldDescriptorProto) (s string) {
+	if field.Options == nil {
+		return ""
+	}
+	for _, ext := range g.soltypeExts {
+		if !proto.HasExtension(field.Options, ext) {
+			continue
+		}
+		v, err := proto.GetExtension(field.Options, ext)
+		if err != nil {
+			Error(err, "invalid soltype option of field", *field.Name)
 		}
+		if s != "" {
+			Fail("more than one soltype option of field", *field.Name)
+		}
+		s = *v.(*string)
 	}
 	return
 }
 
-// get solidity library name from proto package name
+// getFixedLen returns N if field has fixed-size array soltype like "address[N]", 0 otherwise
+func (g *Generator) getFixedLen(field *descriptor.FieldDescriptorProto) int {
+	_, n := splitArrayType(g.getSolTypeOpt(field))
+	return n
+}
+
+// valid solidity identifier
+var solIdentRe = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*$`)
+
+// fixed-size array suffix, eg. [2]
+var fixedArrayRe = regexp.MustCompile(`^(.*)\[([0-9]+)\]$`)
+
+// splitArrayType removes array suffix from solidity type and returns fixed-size array length
+// splitArrayType("address[2]") -> "address", 2
+// splitArrayType("address[]") -> "address", 0
+func splitArrayType(t string) (string, int) {
+	if strings.HasSuffix(t, "[]") {
+		return strings.TrimSuffix(t, "[]"), 0
+	}
+	sub := fixedArrayRe.FindStringSubmatch(t)
+	if sub == nil {
+		return t, 0
+	}
+	n, err := strconv.Atoi(sub[2])
+	if err != nil || n == 0 {
+		Fail("invalid fixed-size array", t)
+	}
+	return sub[1], n
+}
+
+// get solidity library name from proto package name, with libprefix and libsuffix
 // getSolLib("example") -> PbExample
-func getSolLib(pkg string) string {
+func (g *Generator) getSolLib(pkg string) string {
 	if pkg == "" {
 		Fail("empty package name")
 	}
-	libname := "Pb"
+	if lib, ok := g.pkgLibs[pkg]; ok { // set by sol_library file option
+		if lib == g.pblib {
+			Fail("sol_library", lib, "of package", pkg, "conflicts with runtime library")
+		}
+		return lib
+	}
+	libname := g.libPrefix
 	cap := true
 	for _, v := range pkg {
 		if (v >= 'A' && v <= 'Z') || v >= '0' && v <= '9' {
@@ -490,13 +1083,17 @@ func getSolLib(pkg string) string {
 			cap = true
 		}
 	}
+	libname += g.libSuffix
+	if !solIdentRe.MatchString(libname) || libname == g.pblib {
+		Fail("invalid library name", libname, "for package", pkg)
+	}
 	return libname
 }
 
 // get solidity library name from proto package name
 // getSolFile("example") -> PbExample.sol
-func getSolFile(pkg string) string {
-	return getSolLib(pkg) + ".sol"
+func (g *Generator) getSolFile(pkg string) string {
+	return g.getSolLib(pkg) + ".sol"
 }
 
 func getDecFname(name string) string {
@@ -518,17 +1115,6 @@ func sortedTags(m map[int]string) (ret []int) {
 	return
 }
 
-// Iterate over defined extensions and if found ExtName, return its field number
-// otherwise return -1
-func getExtNum(f fdes) int32 {
-	for _, ext := range f.Extension {
-		if *ext.Name == ExtName {
-			return *ext.Number
-		}
-	}
-	return -1
-}
-
 // Is this field repeated?
 func isRepeated(field *descriptor.FieldDescriptorProto) bool {
 	return field.Label != nil && *field.Label == descriptor.FieldDescriptorProto_LABEL_REPEATED
@@ -555,6 +1141,20 @@ func toSolNaming(name *string) string {
 	return s
 }
 
+// toLowerCamel transforms name to lowerCamelCase by words, e.g. var_name_one to varNameOne,
+// URL_value to urlValue, field_2x to field2x, ownerID to ownerId
+func toLowerCamel(name string) string {
+	var s string
+	for i, w := range splitWords(name) {
+		if i == 0 {
+			s += strings.ToLower(w)
+		} else {
+			s += strings.ToUpper(w[:1]) + strings.ToLower(w[1:])
+		}
+	}
+	return s
+}
+
 // ProtoSol is the full proto library, appended in the end of generated .sol file
 const ProtoSol = `
 // runtime proto sol library
@@ -699,6 +1299,66 @@ library Pb {
         assembly { v := mload(add(b, 32)) }
     }
 
+    // well-known types google.protobuf.Timestamp and Duration, seconds (tag 1) and nanos (tag 2).
+    // negative seconds or nanos aren't supported and revert, so do nanos out of 0 to 999,999,999
+    function _timeSeconds(bytes memory b) internal pure returns (uint) {
+        (uint secs, ) = _time(b);
+        return secs;
+    }
+
+    function _timeNanos(bytes memory b) internal pure returns (uint) {
+        (uint secs, uint nanos) = _time(b);
+        return secs * 1000000000 + nanos;
+    }
+
+    function _time(bytes memory b) internal pure returns (uint secs, uint nanos) {
+        Buffer memory buf = fromBytes(b);
+        while (hasMore(buf)) {
+            (uint tag, WireType wire) = decKey(buf);
+            if (tag == 1) { secs = decVarint(buf); }
+            else if (tag == 2) { nanos = decVarint(buf); }
+            else { skipValue(buf, wire); }
+        }
+        // int64 on the wire, negative is 2^64 wrapped
+        require(secs < 2**63 && nanos < 2**63);  // negative time isn't supported
+        require(nanos < 1000000000);  // nanos must be 0 to 999,999,999
+    }
+
+    // well-known wrapper types like google.protobuf.UInt64Value, value is tag 1
+    function _varintValue(bytes memory b) internal pure returns (uint v) {
+        Buffer memory buf = fromBytes(b);
+        while (hasMore(buf)) {
+            (uint tag, WireType wire) = decKey(buf);
+            if (tag == 1) { v = decVarint(buf); }
+            else { skipValue(buf, wire); }
+        }
+    }
+
+    function _bytesValue(bytes memory b) internal pure returns (bytes memory v) {
+        Buffer memory buf = fromBytes(b);
+        while (hasMore(buf)) {
+            (uint tag, WireType wire) = decKey(buf);
+            if (tag == 1) { v = decBytes(buf); }
+            else { skipValue(buf, wire); }
+        }
+    }
+
+    // well-known type google.protobuf.Any
+    struct Any {
+        string typeUrl;  // tag: 1
+        bytes value;  // tag: 2
+    }
+
+    function _any(bytes memory b) internal pure returns (Any memory a) {
+        Buffer memory buf = fromBytes(b);
+        while (hasMore(buf)) {
+            (uint tag, WireType wire) = decKey(buf);
+            if (tag == 1) { a.typeUrl = string(decBytes(buf)); }
+            else if (tag == 2) { a.value = decBytes(buf); }
+            else { skipValue(buf, wire); }
+        }
+    }
+
     // uint[] to uint8[]
     function uint8s(uint[] memory arr) internal pure returns (uint8[] memory t) {
         t = new uint8[](arr.length);
@@ -721,3 +1381,249 @@ library Pb {
     }
 }
 `
+
+// ProtoSol08 is the full proto library for solc 0.8, appended in the end of generated .sol file.
+// Same functions as ProtoSol but uses custom errors, unchecked blocks and memory-safe assembly.
+const ProtoSol08 = `
+// runtime proto sol library
+library Pb {
+    enum WireType { Varint, Fixed64, LengthDelim, StartGroup, EndGroup, Fixed32 }
+
+    error InvalidVarint();  // varint is longer than 10 bytes
+    error OutOfBounds();  // read beyond the end of buffer
+    error UnsupportedWireType(uint wire);
+    error InvalidLength(uint length);  // bytes length doesn't match soltype
+    error WrongArrayLength(uint tag, uint length);  // element count doesn't match fixed-size array
+    error InvalidEnumValue(uint value);  // proto enum number isn't defined
+    error UnexpectedTypeUrl(string typeUrl);  // google.protobuf.Any isn't the message to unpack
+    error NegativeTime();  // google.protobuf.Timestamp or Duration is negative
+    error InvalidNanos(uint nanos);  // nanos of google.protobuf.Timestamp or Duration isn't 0 to 999,999,999
+
+    struct Buffer {
+        uint idx;  // the start index of next read. when idx=b.length, we're done
+        bytes b;   // hold serialized proto msg, readonly
+    }
+
+    // create a new in-memory Buffer object from raw msg bytes
+    function fromBytes(bytes memory raw) internal pure returns (Buffer memory buf) {
+        buf.b = raw;
+        buf.idx = 0;
+    }
+
+    // whether there are unread bytes
+    function hasMore(Buffer memory buf) internal pure returns (bool) {
+        return buf.idx < buf.b.length;
+    }
+
+    // decode current field number and wiretype
+    function decKey(Buffer memory buf) internal pure returns (uint tag, WireType wiretype) {
+        uint v = decVarint(buf);
+        tag = v / 8;
+        if (v & 7 > uint(type(WireType).max)) revert UnsupportedWireType(v & 7);
+        wiretype = WireType(v & 7);
+    }
+
+    // count tag occurrences, return an array due to no memory map support
+    // have to create array for (maxtag+1) size. cnts[tag] = occurrences
+    // should keep buf.idx unchanged because this is only a count function
+    function cntTags(Buffer memory buf, uint maxtag) internal pure returns (uint[] memory cnts) {
+        uint originalIdx = buf.idx;
+        cnts = new uint[](maxtag + 1);  // protobuf's tags are from 1 rather than 0
+        uint tag;
+        WireType wire;
+        while (hasMore(buf)) {
+            (tag, wire) = decKey(buf);
+            unchecked { cnts[tag] += 1; }  // can't exceed buf length
+            skipValue(buf, wire);
+        }
+        buf.idx = originalIdx;
+    }
+
+    // read varint from current buf idx, move buf.idx to next read, return the int value
+    function decVarint(Buffer memory buf) internal pure returns (uint v) {
+        bytes10 tmp;  // proto int is at most 10 bytes (7 bits can be used per byte)
+        bytes memory bb = buf.b;  // get buf.b mem addr to use in assembly
+        v = buf.idx;  // use v to save one additional uint variable
+        assembly ("memory-safe") {
+            tmp := mload(add(add(bb, 32), v)) // load 10 bytes from buf.b[buf.idx] to tmp
+        }
+        uint b; // store current byte content
+        v = 0; // reset to 0 for return value
+        unchecked {  // i is at most 10 and buf.idx is at most buf.b.length, can't overflow
+            for (uint i = 0; i < 10; ++i) {
+                assembly ("memory-safe") {
+                    b := byte(i, tmp)  // don't use tmp[i] because it does bound check and costs extra
+                }
+                v |= (b & 0x7F) << (i * 7);
+                if (b & 0x80 == 0) {
+                    buf.idx += i + 1;
+                    if (buf.idx > buf.b.length) revert OutOfBounds();  // truncated varint
+                    return v;
+                }
+            }
+        }
+        revert InvalidVarint(); // i=10, invalid varint stream
+    }
+
+    // read length delimited field and return bytes
+    function decBytes(Buffer memory buf) internal pure returns (bytes memory b) {
+        uint len = decVarint(buf);
+        uint end = buf.idx + len;  // checked arithmetic reverts on overflow
+        if (end > buf.b.length) revert OutOfBounds();
+        b = new bytes(len);
+        bytes memory bufB = buf.b;  // get buf.b mem addr to use in assembly
+        uint bStart;
+        uint bufBStart = buf.idx;
+        assembly ("memory-safe") {
+            bStart := add(b, 32)
+            bufBStart := add(add(bufB, 32), bufBStart)
+        }
+        unchecked {  // i < len <= buf.b.length
+            for (uint i = 0; i < len; i += 32) {
+                assembly ("memory-safe") {
+                    mstore(add(bStart, i), mload(add(bufBStart, i)))
+                }
+            }
+        }
+        buf.idx = end;
+    }
+
+    // return packed ints
+    function decPacked(Buffer memory buf) internal pure returns (uint[] memory t) {
+        uint len = decVarint(buf);
+        uint end = buf.idx + len;  // checked arithmetic reverts on overflow
+        if (end > buf.b.length) revert OutOfBounds();
+        // array in memory must be init w/ known length
+        // so we have to create a tmp array w/ max possible len first
+        uint[] memory tmp = new uint[](len);
+        uint i = 0; // count how many ints are there
+        unchecked {  // i <= len as each varint takes at least 1 byte
+            while (buf.idx < end) {
+                tmp[i] = decVarint(buf);
+                i++;
+            }
+            t = new uint[](i); // init t with correct length
+            for (uint j = 0; j < i; j++) {
+                t[j] = tmp[j];
+            }
+        }
+        return t;
+    }
+
+    // move idx pass current value field, to beginning of next tag or msg end
+    function skipValue(Buffer memory buf, WireType wire) internal pure {
+        if (wire == WireType.Varint) { decVarint(buf); }
+        else if (wire == WireType.LengthDelim) {
+            uint len = decVarint(buf);
+            buf.idx += len; // skip len bytes value data, checked arithmetic reverts on overflow
+            if (buf.idx > buf.b.length) revert OutOfBounds();
+        } else { revert UnsupportedWireType(uint(wire)); }
+    }
+
+    // type conversion help utils
+    function _bool(uint x) internal pure returns (bool v) {
+        return x != 0;
+    }
+
+    function _uint256(bytes memory b) internal pure returns (uint256 v) {
+        if (b.length > 32) revert InvalidLength(b.length);
+        assembly ("memory-safe") { v := mload(add(b, 32)) }  // load all 32bytes to v
+        v = v >> (8 * (32 - b.length));  // only first b.length is valid
+    }
+
+    function _address(bytes memory b) internal pure returns (address v) {
+        if (b.length != 20) revert InvalidLength(b.length);
+        v = address(bytes20(b));
+    }
+
+    function _addressPayable(bytes memory b) internal pure returns (address payable v) {
+        v = payable(_address(b));
+    }
+
+    function _bytes32(bytes memory b) internal pure returns (bytes32 v) {
+        if (b.length != 32) revert InvalidLength(b.length);
+        v = bytes32(b);
+    }
+
+    // well-known types google.protobuf.Timestamp and Duration, seconds (tag 1) and nanos (tag 2).
+    // negative seconds or nanos aren't supported and revert, so do nanos out of 0 to 999,999,999
+    function _timeSeconds(bytes memory b) internal pure returns (uint) {
+        (uint secs, ) = _time(b);
+        return secs;
+    }
+
+    function _timeNanos(bytes memory b) internal pure returns (uint) {
+        (uint secs, uint nanos) = _time(b);
+        return secs * 1000000000 + nanos;
+    }
+
+    function _time(bytes memory b) internal pure returns (uint secs, uint nanos) {
+        Buffer memory buf = fromBytes(b);
+        while (hasMore(buf)) {
+            (uint tag, WireType wire) = decKey(buf);
+            if (tag == 1) { secs = decVarint(buf); }
+            else if (tag == 2) { nanos = decVarint(buf); }
+            else { skipValue(buf, wire); }
+        }
+        // int64 on the wire, negative is 2^64 wrapped
+        if (secs >= 1 << 63 || nanos >= 1 << 63) revert NegativeTime();
+        if (nanos >= 1000000000) revert InvalidNanos(nanos);
+    }
+
+    // well-known wrapper types like google.protobuf.UInt64Value, value is tag 1
+    function _varintValue(bytes memory b) internal pure returns (uint v) {
+        Buffer memory buf = fromBytes(b);
+        while (hasMore(buf)) {
+            (uint tag, WireType wire) = decKey(buf);
+            if (tag == 1) { v = decVarint(buf); }
+            else { skipValue(buf, wire); }
+        }
+    }
+
+    function _bytesValue(bytes memory b) internal pure returns (bytes memory v) {
+        Buffer memory buf = fromBytes(b);
+        while (hasMore(buf)) {
+            (uint tag, WireType wire) = decKey(buf);
+            if (tag == 1) { v = decBytes(buf); }
+            else { skipValue(buf, wire); }
+        }
+    }
+
+    // well-known type google.protobuf.Any
+    struct Any {
+        string typeUrl;  // tag: 1
+        bytes value;  // tag: 2
+    }
+
+    function _any(bytes memory b) internal pure returns (Any memory a) {
+        Buffer memory buf = fromBytes(b);
+        while (hasMore(buf)) {
+            (uint tag, WireType wire) = decKey(buf);
+            if (tag == 1) { a.typeUrl = string(decBytes(buf)); }
+            else if (tag == 2) { a.value = decBytes(buf); }
+            else { skipValue(buf, wire); }
+        }
+    }
+
+    // uint[] to uint8[]
+    function uint8s(uint[] memory arr) internal pure returns (uint8[] memory t) {
+        t = new uint8[](arr.length);
+        unchecked { for (uint i = 0; i < t.length; i++) { t[i] = uint8(arr[i]); } }
+    }
+
+    function uint32s(uint[] memory arr) internal pure returns (uint32[] memory t) {
+        t = new uint32[](arr.length);
+        unchecked { for (uint i = 0; i < t.length; i++) { t[i] = uint32(arr[i]); } }
+    }
+
+    function uint64s(uint[] memory arr) internal pure returns (uint64[] memory t) {
+        t = new uint64[](arr.length);
+        unchecked { for (uint i = 0; i < t.length; i++) { t[i] = uint64(arr[i]); } }
+    }
+
+    function bools(uint[] memory arr) internal pure returns (bool[] memory t) {
+        t = new bool[](arr.length);
+        unchecked { for (uint i = 0; i < t.length; i++) { t[i] = arr[i] != 0; } }
+    }
+}
+`
@@ -0,0 +1,130 @@
+// protoc-gen-sol by Celer Network Team
+
+package generator
+
+import (
+	"log"
+	"regexp"
+	"strings"
+)
+
+// solKeywords are solidity keywords and reserved words, can't be used as any identifier
+var solKeywords = toSet(`abstract after alias anonymous apply as assembly auto bool break byte bytes
+calldata case catch constant constructor continue contract copyof days default define delete do else
+emit enum ether event external fallback false final finney for function gwei hex hours if immutable
+implements import in indexed inline interface internal is let library macro mapping match memory
+minutes modifier mutable new null of override partial payable pragma private promise public pure
+receive reference relocatable return returns sealed seconds sizeof static storage string struct
+supports switch szabo throw true try type typedef typeof unchecked using var view virtual weeks wei
+while years address int uint fixed ufixed`)
+
+// elementary types with size like uint8, bytes32 and fixed128x18 are keywords too
+var solSizedTypeRe = regexp.MustCompile(`^(u?int[0-9]+|bytes[0-9]+|u?fixed[0-9]+x[0-9]+)$`)
+
+// solBuiltins are global names, struct or enum with the same name shadows them
+var solBuiltins = toSet(`abi block blockhash gasleft msg now tx this super selfdestruct suicide
+sha3 keccak256 sha256 ripemd160 ecrecover addmod mulmod assert require revert`)
+
+// genLocals are parameter and local variable names in generated functions, struct or enum
+// with the same name is shadowed by them. see generateMsg and generateEnum
+var genLocals = toSet(`raw m buf tag wire cnts tmp i arr t num v`)
+
+func toSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+// whether name is a solidity keyword
+func isSolKeyword(name string) bool {
+	return solKeywords[name] || solSizedTypeRe.MatchString(name)
+}
+
+// warned records logged rename warnings, names are resolved more than once but only need to warn once
+var warned = make(map[string]bool)
+
+func warnRename(what, name, reason string) {
+	msg := "warning: rename " + what + " to " + name + "_ because " + name + reason
+	if !warned[msg] {
+		warned[msg] = true
+		log.Print(msg)
+	}
+}
+
+// sanitizeMember renames struct member or enum value name if it's solidity keyword
+// by appending _, eg. address -> address_. what is for log message eg. field address
+func sanitizeMember(name, what string) string {
+	if !isSolKeyword(name) {
+		return name
+	}
+	warnRename(what, name, " is solidity keyword")
+	return name + "_"
+}
+
+// sanitizeType renames struct or enum name if it's solidity keyword, builtin or
+// generated local variable name by appending _, eg. msg -> msg_
+func sanitizeType(name, what string) string {
+	if !isSolKeyword(name) && !solBuiltins[name] && !genLocals[name] {
+		return name
+	}
+	warnRename(what, name, " is solidity keyword or reserved name")
+	return name + "_"
+}
+
+// nameScope detects identifier collisions in the same solidity scope, eg. a library or a struct
+type nameScope struct {
+	scope string            // eg. library PbFoo, for error message
+	names map[string]string // identifier to what it is eg. struct Foo
+}
+
+func newNameScope(scope string) *nameScope {
+	return &nameScope{scope: scope, names: make(map[string]string)}
+}
+
+// add identifier name declared by what, fail if it's already declared in the scope
+func (s *nameScope) add(name, what string) {
+	if prev, ok := s.names[name]; ok {
+		Fail("name collision in", s.scope+":", prev, "and", what, "are both", name+".",
+			"use sol_struct, sol_enum, sol_name or sol_enum_value option to rename one of them")
+	}
+	s.names[name] = what
+}
+
+// splitWords splits name to words by _ and camel case humps, eg. URL_value -> URL value,
+// HTTPServer -> HTTP Server, fooBar2x -> foo Bar2x
+func splitWords(name string) (words []string) {
+	for _, part := range strings.Split(name, "_") {
+		start := 0
+		for i := 1; i < len(part); i++ {
+			if !isUpper(part[i]) {
+				continue
+			}
+			// new word starts at upper case letter after lower case letter or digit,
+			// or before lower case letter in a run of upper case letters
+			if !isUpper(part[i-1]) || (i+1 < len(part) && isLower(part[i+1])) {
+				words = append(words, part[start:i])
+				start = i
+			}
+		}
+		if start < len(part) {
+			words = append(words, part[start:])
+		}
+	}
+	return
+}
+
+func isUpper(c byte) bool { return c >= 'A' && c <= 'Z' }
+
+func isLower(c byte) bool { return c >= 'a' && c <= 'z' }
+
+// toSnake transforms name to snake_case, eg. fooBar -> foo_bar
+func toSnake(name string) string {
+	return strings.ToLower(strings.Join(splitWords(name), "_"))
+}
+
+// toUpperSnake transforms name to UPPER_SNAKE_CASE, eg. ChannelStatus -> CHANNEL_STATUS
+func toUpperSnake(name string) string {
+	return strings.ToUpper(strings.Join(splitWords(name), "_"))
+}
@@ -0,0 +1,310 @@
+// protoc-gen-sol by Celer Network Team
+
+package generator
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// OptionsProto is the proto file shipped with protoc-gen-sol that defines its options
+const OptionsProto = "sol/options.proto"
+
+// E_Soltype is the soltype extension defined in sol/options.proto
+var E_Soltype = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.FieldOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         54321,
+	Name:          "sol.soltype",
+	Tag:           "bytes,54321,opt,name=soltype",
+	Filename:      OptionsProto,
+}
+
+// E_SolLibrary is the sol_library file option defined in sol/options.proto
+var E_SolLibrary = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.FileOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         54322,
+	Name:          "sol.sol_library",
+	Tag:           "bytes,54322,opt,name=sol_library",
+	Filename:      OptionsProto,
+}
+
+// E_SolStruct is the sol_struct message option defined in sol/options.proto
+var E_SolStruct = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.MessageOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         54323,
+	Name:          "sol.sol_struct",
+	Tag:           "bytes,54323,opt,name=sol_struct",
+	Filename:      OptionsProto,
+}
+
+// E_SolName is the sol_name field option defined in sol/options.proto
+var E_SolName = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.FieldOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         54324,
+	Name:          "sol.sol_name",
+	Tag:           "bytes,54324,opt,name=sol_name",
+	Filename:      OptionsProto,
+}
+
+// E_SolEnum is the sol_enum enum option defined in sol/options.proto
+var E_SolEnum = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.EnumOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         54325,
+	Name:          "sol.sol_enum",
+	Tag:           "bytes,54325,opt,name=sol_enum",
+	Filename:      OptionsProto,
+}
+
+// E_SolEnumValue is the sol_enum_value enum value option defined in sol/options.proto
+var E_SolEnumValue = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.EnumValueOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         54326,
+	Name:          "sol.sol_enum_value",
+	Tag:           "bytes,54326,opt,name=sol_enum_value",
+	Filename:      OptionsProto,
+}
+
+// E_SolOpenEnum is the sol_open_enum enum option defined in sol/options.proto
+var E_SolOpenEnum = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.EnumOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         54327,
+	Name:          "sol.sol_open_enum",
+	Tag:           "bytes,54327,opt,name=sol_open_enum",
+	Filename:      OptionsProto,
+}
+
+// E_SolOpenEnumField is the sol_open_enum_field field option defined in sol/options.proto
+var E_SolOpenEnumField = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.FieldOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         54328,
+	Name:          "sol.sol_open_enum_field",
+	Tag:           "bytes,54328,opt,name=sol_open_enum_field",
+	Filename:      OptionsProto,
+}
+
+// E_SolSkip is the sol_skip field option defined in sol/options.proto
+var E_SolSkip = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.FieldOptions)(nil),
+	ExtensionType: (*bool)(nil),
+	Field:         54329,
+	Name:          "sol.sol_skip",
+	Tag:           "varint,54329,opt,name=sol_skip",
+	Filename:      OptionsProto,
+}
+
+func init() {
+	proto.RegisterExtension(E_Soltype)
+	proto.RegisterExtension(E_SolLibrary)
+	proto.RegisterExtension(E_SolStruct)
+	proto.RegisterExtension(E_SolName)
+	proto.RegisterExtension(E_SolEnum)
+	proto.RegisterExtension(E_SolEnumValue)
+	proto.RegisterExtension(E_SolOpenEnum)
+	proto.RegisterExtension(E_SolOpenEnumField)
+	proto.RegisterExtension(E_SolSkip)
+}
+
+// findSolTypeExts returns soltype extensions to google.protobuf.FieldOptions declared in any proto file,
+// including sol/options.proto and the ones a .proto declares by itself like string soltype = 1001;
+func findSolTypeExts(files []*descriptor.FileDescriptorProto) (exts []*proto.ExtensionDesc) {
+	for _, f := range files {
+		for _, ext := range f.Extension {
+			if ext.GetName() != ExtName || ext.GetExtendee() != ".google.protobuf.FieldOptions" {
+				continue
+			}
+			if ext.GetType() != descriptor.FieldDescriptorProto_TYPE_STRING {
+				Fail("soltype extension must be string in", f.GetName())
+			}
+			exts = append(exts, getExtDesc(ext.GetNumber(), f.GetPackage()+"."+ExtName))
+		}
+	}
+	return
+}
+
+// getExtDesc returns registered string extension to FieldOptions at field number num
+// register a new one if it's not registered yet
+func getExtDesc(num int32, name string) *proto.ExtensionDesc {
+	if desc, ok := proto.RegisteredExtensions((*descriptor.FieldOptions)(nil))[num]; ok {
+		if desc.Name != name {
+			Fail("extension number", fmt.Sprint(num), "is used by both", desc.Name, "and", name)
+		}
+		return desc
+	}
+	desc := &proto.ExtensionDesc{
+		ExtendedType:  (*descriptor.FieldOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         num,
+		Name:          name,
+		Tag:           fmt.Sprintf("bytes,%d,opt,name=%s", num, ExtName),
+	}
+	proto.RegisterExtension(desc)
+	return desc
+}
+
+// getStrOpt returns string option ext in opts, "" if not set
+// opts must not be nil. what is for error message eg. message Foo
+func getStrOpt(opts proto.Message, ext *proto.ExtensionDesc, what string) string {
+	if !proto.HasExtension(opts, ext) {
+		return ""
+	}
+	v, err := proto.GetExtension(opts, ext)
+	if err != nil {
+		Error(err, "invalid", ext.Name, "option of", what)
+	}
+	return *v.(*string)
+}
+
+// getNameOpt returns solidity name option ext in opts, "" if not set
+// opts must not be nil. what is for error message eg. message Foo
+func getNameOpt(opts proto.Message, ext *proto.ExtensionDesc, what string) string {
+	name := getStrOpt(opts, ext, what)
+	if name != "" && !solIdentRe.MatchString(name) {
+		Fail("invalid", ext.Name, name, "of", what)
+	}
+	return name
+}
+
+// msgName returns solidity struct name of message, sol_struct option or message name
+func msgName(m msgdes) string {
+	name := *m.Name
+	if m.Options != nil {
+		if opt := getNameOpt(m.Options, E_SolStruct, "message "+*m.Name); opt != "" {
+			name = opt
+		}
+	}
+	return sanitizeType(name, "message "+*m.Name)
+}
+
+// enumName returns solidity enum name, sol_enum option or proto enum name
+func enumName(e enumdes) string {
+	name := *e.Name
+	if e.Options != nil {
+		if opt := getNameOpt(e.Options, E_SolEnum, "enum "+*e.Name); opt != "" {
+			name = opt
+		}
+	}
+	return sanitizeType(name, "enum "+*e.Name)
+}
+
+// enumValueNames returns solidity enum member names of all values of e, sol_enum_value option or
+// proto enum value name. if stripenumprefix is set, enum name prefix is removed eg. STATUS_OPEN -> OPEN
+// unless it makes the names invalid or not unique
+func (g *Generator) enumValueNames(e enumdes) []string {
+	if !g.stripEnumPfx {
+		return enumValueNames(e, "")
+	}
+	prefix := toUpperSnake(*e.Name) + "_"
+	names := enumValueNames(e, prefix)
+	seen := make(map[string]bool)
+	for _, n := range names {
+		if !solIdentRe.MatchString(n) || seen[n] {
+			msg := "warning: keep enum " + *e.Name + " value names because stripping prefix " + prefix + " makes " + n + " invalid or not unique"
+			if !warned[msg] {
+				warned[msg] = true
+				log.Print(msg)
+			}
+			return enumValueNames(e, "")
+		}
+		seen[n] = true
+	}
+	return names
+}
+
+// enumValueNames returns enum member names with prefix removed from proto value names
+func enumValueNames(e enumdes, prefix string) (names []string) {
+	for _, v := range e.Value {
+		what := "enum value " + *v.Name
+		name := strings.TrimPrefix(*v.Name, prefix)
+		if v.Options != nil {
+			if opt := getNameOpt(v.Options, E_SolEnumValue, what); opt != "" {
+				name = opt
+			}
+		}
+		names = append(names, sanitizeMember(name, what))
+	}
+	return
+}
+
+// fieldName returns solidity struct member name, sol_name option or field name by naming strategy
+func (g *Generator) fieldName(f *descriptor.FieldDescriptorProto) string {
+	var name string
+	switch g.naming {
+	case "proto":
+		name = *f.Name
+	case "json":
+		name = f.GetJsonName()
+	case "snake":
+		name = toSnake(*f.Name)
+	case "lowercamel":
+		name = toLowerCamel(*f.Name)
+	}
+	if name == "" { // default or json_name isn't set
+		name = toSolNaming(f.Name)
+	}
+	if f.Options != nil {
+		if opt := getNameOpt(f.Options, E_SolName, "field "+*f.Name); opt != "" {
+			name = opt
+		}
+	}
+	return sanitizeMember(name, "field "+*f.Name)
+}
+
+// findSolLibraries returns package to its sol_library file option
+func findSolLibraries(files []*descriptor.FileDescriptorProto) map[string]string {
+	libs := make(map[string]string)
+	for _, f := range files {
+		if f.Options == nil {
+			continue
+		}
+		name := getNameOpt(f.Options, E_SolLibrary, "file "+f.GetName())
+		if name == "" {
+			continue
+		}
+		if lib, ok := libs[f.GetPackage()]; ok && lib != name {
+			Fail("package", f.GetPackage(), "has different sol_library", lib, name)
+		}
+		libs[f.GetPackage()] = name
+	}
+	return libs
+}
+
+// isSkipped returns whether field is left out of solidity struct, by sol_skip option,
+// or deprecated option if skipdeprecated param is set. skipped field is decoded as unknown field
+func (g *Generator) isSkipped(f *descriptor.FieldDescriptorProto) bool {
+	if f.Options == nil {
+		return false
+	}
+	if g.skipDeprecated && f.Options.GetDeprecated() {
+		return true
+	}
+	if !proto.HasExtension(f.Options, E_SolSkip) {
+		return false
+	}
+	v, err := proto.GetExtension(f.Options, E_SolSkip)
+	if err != nil {
+		Error(err, "invalid sol_skip option of field", *f.Name)
+	}
+	return *v.(*bool)
+}
+
+// solFields returns fields of m that are in solidity struct
+func (g *Generator) solFields(m msgdes) (fields []*descriptor.FieldDescriptorProto) {
+	for _, f := range m.Field {
+		if !g.isSkipped(f) {
+			fields = append(fields, f)
+		}
+	}
+	return
+}
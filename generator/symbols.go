@@ -0,0 +1,309 @@
+// protoc-gen-sol by Celer Network Team
+
+package generator
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// symbol is a top level proto message or enum and its solidity definition
+type symbol struct {
+	pkg   string  // proto package
+	name  string  // solidity struct or enum name, may be renamed by sol_struct or sol_enum option
+	lib   string  // solidity library name
+	file  string  // generated .sol file name
+	proto string  // proto file name that defines it
+	msg   msgdes  // message descriptor, nil for enum
+	enum  enumdes // enum descriptor, nil for message
+}
+
+// buildSymbols maps fully qualified proto name eg. .pkg.Msg of every message and enum
+// in the request to its solidity definition. It doesn't matter how a type is imported
+// (eg. import public), type references and imports are all resolved by this table.
+func (g *Generator) buildSymbols() {
+	for _, f := range g.Request.ProtoFile {
+		prefix := "."
+		if f.GetPackage() != "" {
+			prefix += f.GetPackage() + "."
+		}
+		add := func(protoName, name string) *symbol {
+			s := &symbol{
+				pkg:   f.GetPackage(),
+				name:  name,
+				proto: f.GetName(),
+			}
+			// well-known types like descriptor.proto aren't generated, and generated file must have package
+			if !isWellKnown(f) && s.pkg != "" {
+				s.lib = g.getSolLib(s.pkg)
+				s.file = g.getSolFile(s.pkg)
+			}
+			g.symbols[prefix+protoName] = s
+			return s
+		}
+		for _, m := range f.MessageType {
+			add(m.GetName(), msgName(m)).msg = m
+		}
+		for _, e := range f.EnumType {
+			add(e.GetName(), enumName(e)).enum = e
+		}
+	}
+}
+
+// lookup returns solidity definition of proto type name, which is fully qualified like .pkg.Msg
+func (g *Generator) lookup(typeName string) *symbol {
+	s, ok := g.symbols[typeName]
+	if !ok {
+		Fail("unknown type", typeName, "nested message or enum definition isn't supported")
+	}
+	if s.lib == "" {
+		Fail("unsupported type", typeName, "of well-known type or file without package")
+	}
+	return s
+}
+
+// whether f is google/protobuf/*.proto
+func isWellKnown(f *descriptor.FileDescriptorProto) bool {
+	return f.GetPackage() == "google.protobuf"
+}
+
+// checkCycles fails if a message to generate references itself directly or through other messages,
+// as solidity struct can't contain itself. A field with soltype "bytes" breaks the cycle since it
+// keeps the raw message bytes, which can be decoded later by the message decoder.
+func (g *Generator) checkCycles() {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var path []string // fully qualified field names from the root to current message
+	var visit func(typeName string)
+	visit = func(typeName string) {
+		sym, ok := g.symbols[typeName]
+		if !ok || sym.msg == nil || sym.lib == "" {
+			return // enum, well-known or unknown type that fails later
+		}
+		switch state[typeName] {
+		case done:
+			return
+		case visiting:
+			cycle := []string{typeName[1:]} // without leading .
+			for i := len(path) - 1; i >= 0; i-- {
+				cycle = append([]string{path[i][1:]}, cycle...)
+				if strings.HasPrefix(path[i], typeName+".") {
+					break
+				}
+			}
+			Fail("recursive message type", strings.Join(cycle, " -> ")+",",
+				"solidity struct can't contain itself, set (sol.soltype) = \"bytes\" on one of the fields to keep raw bytes and decode it lazily")
+		}
+		state[typeName] = visiting
+		for _, f := range g.solFields(sym.msg) {
+			if f.GetType() != descriptor.FieldDescriptorProto_TYPE_MESSAGE || g.getSolTypeOpt(f) == "bytes" {
+				continue
+			}
+			path = append(path, typeName+"."+f.GetName())
+			visit(f.GetTypeName())
+			path = path[:len(path)-1]
+		}
+		state[typeName] = done
+	}
+	for _, name := range g.Request.FileToGenerate {
+		f := (*descriptor.FileDescriptorProto)(g.files[name])
+		for _, m := range f.MessageType {
+			if g.shouldOutput(f.GetPackage(), m.GetName()) {
+				visit("." + f.GetPackage() + "." + m.GetName())
+			}
+		}
+	}
+}
+
+// selectOutputs sets messages and enums to generate by msg and exclude params. msg selects messages,
+// and every message and enum they reference directly or indirectly is also generated, across packages.
+// exclude leaves messages or enums out, generator fails if a generated message references excluded one.
+// names can be short like Msg3 for messages or enums of files to generate, or fully qualified like mytest.Msg3.
+// msg@scope selects messages of a package or .proto file instead of global msg, short names are within scope
+func (g *Generator) selectOutputs() {
+	if len(g.onlymsgs) == 0 && len(g.excludes) == 0 && !g.hasScoped("msg") && !g.hasScoped("exclude") {
+		return
+	}
+	excluded := g.resolveNames(g.excludes, "exclude", g.Request.FileToGenerate)
+	roots := make(map[string]bool)
+	if len(g.onlymsgs) > 0 {
+		roots = g.resolveNames(g.onlymsgs, "msg", g.Request.FileToGenerate)
+	}
+	for _, files := range g.pkgFilesToGenerate() {
+		scopedMsgs, scopedExcludes := make(map[string]bool), make(map[string]bool)
+		for _, p := range g.scoped {
+			if p.key == "msg" && inScope(p.scope, files) {
+				scopedMsgs[p.value] = true
+			} else if p.key == "exclude" && inScope(p.scope, files) {
+				scopedExcludes[p.value] = true
+			}
+		}
+		var names []string
+		for _, f := range files {
+			names = append(names, *f.Name)
+		}
+		for name := range g.resolveNames(scopedExcludes, "exclude@"+*files[0].Package, names) {
+			excluded[name] = true
+		}
+		if len(scopedMsgs) > 0 {
+			for name := range g.resolveNames(scopedMsgs, "msg@"+*files[0].Package, names) {
+				roots[name] = true
+			}
+		} else if len(g.onlymsgs) == 0 {
+			// no whitelist for this package, all messages and enums are selected
+			for _, f := range files {
+				for _, m := range f.MessageType {
+					roots["."+*f.Package+"."+*m.Name] = true
+				}
+				for _, e := range f.EnumType {
+					roots["."+*f.Package+"."+*e.Name] = true
+				}
+			}
+		}
+	}
+	var sorted []string
+	for name := range roots {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	generated := make(map[string]bool)
+	for _, name := range g.Request.FileToGenerate {
+		generated[name] = true
+	}
+	g.outputs = make(map[string]bool)
+	var add func(typeName, from string)
+	add = func(typeName, from string) {
+		if g.outputs[typeName] {
+			return
+		}
+		if excluded[typeName] {
+			Fail("excluded", typeName[1:], "is referenced by", from)
+		}
+		sym, ok := g.symbols[typeName]
+		if !ok || sym.lib == "" {
+			return // well-known or unknown type, unknown type fails later
+		}
+		if !generated[sym.proto] {
+			if from == "" {
+				Fail("selected", typeName[1:], "isn't generated as", sym.proto, "isn't in files to generate")
+			}
+			msg := "warning: " + typeName[1:] + " referenced by " + from + " isn't generated as " + sym.proto +
+				" isn't in files to generate, it must be generated with package " + sym.pkg
+			if !warned[msg] {
+				warned[msg] = true
+				log.Print(msg)
+			}
+			return
+		}
+		g.outputs[typeName] = true
+		if sym.msg == nil {
+			return
+		}
+		for _, f := range g.solFields(sym.msg) {
+			if f.TypeName != nil {
+				add(f.GetTypeName(), typeName[1:]+"."+f.GetName())
+			}
+		}
+	}
+	for _, r := range sorted {
+		if !excluded[r] {
+			add(r, "")
+		}
+	}
+}
+
+// hasScoped returns whether param key is scoped to any package or file
+func (g *Generator) hasScoped(key string) bool {
+	for _, p := range g.scoped {
+		if p.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// checkScopes fails if a scoped param doesn't match any package or .proto file to generate
+func (g *Generator) checkScopes() {
+	for _, p := range g.scoped {
+		found := false
+		for _, files := range g.pkgFilesToGenerate() {
+			found = found || inScope(p.scope, files)
+		}
+		if !found {
+			Fail("scope", p.scope, "of param", p.key, "isn't a package or .proto file to generate")
+		}
+	}
+}
+
+// checkPackages fails if only some .proto files of a package are to generate, as the .sol file of
+// the package would miss types of other files, and overwrite the one generated with all of them
+func (g *Generator) checkPackages() {
+	for _, files := range g.pkgFilesToGenerate() {
+		pkg := (*descriptor.FileDescriptorProto)(files[0]).GetPackage()
+		var all []string
+		missing := false
+		for _, f := range g.Request.ProtoFile {
+			if f.GetPackage() != pkg {
+				continue
+			}
+			all = append(all, f.GetName())
+			missing = missing || !inArray(f.GetName(), g.Request.FileToGenerate)
+		}
+		if missing {
+			Fail("package", pkg, "spans", strings.Join(all, ", ")+";", "pass all of them")
+		}
+	}
+}
+
+// pkgFilesToGenerate returns files to generate grouped by package, in order of first appearance
+func (g *Generator) pkgFilesToGenerate() (pkgFiles [][]fdes) {
+	idx := make(map[string]int)
+	for _, name := range g.Request.FileToGenerate {
+		f := g.files[name]
+		pkg := (*descriptor.FileDescriptorProto)(f).GetPackage()
+		if i, ok := idx[pkg]; ok {
+			pkgFiles[i] = append(pkgFiles[i], f)
+			continue
+		}
+		idx[pkg] = len(pkgFiles)
+		pkgFiles = append(pkgFiles, []fdes{f})
+	}
+	return
+}
+
+// resolveNames returns fully qualified names with leading . of messages or enums in names of param.
+// name without package matches top level messages and enums of files, and fails if it matches more than one
+func (g *Generator) resolveNames(names map[string]bool, param string, files []string) map[string]bool {
+	generated := make(map[string]bool)
+	for _, f := range files {
+		generated[f] = true
+	}
+	ret := make(map[string]bool)
+	for name := range names {
+		if _, ok := g.symbols["."+name]; ok {
+			ret["."+name] = true
+			continue
+		}
+		var found []string
+		for fqn, sym := range g.symbols {
+			if generated[sym.proto] && fqn == "."+sym.pkg+"."+name {
+				found = append(found, fqn[1:])
+			}
+		}
+		if len(found) == 0 {
+			Fail("unknown message or enum", name, "in param", param)
+		}
+		if len(found) > 1 {
+			sort.Strings(found)
+			Fail("ambiguous name", name, "in param", param, "matches", strings.Join(found, ", ")+", use fully qualified name")
+		}
+		ret["."+found[0]] = true
+	}
+	return ret
+}
@@ -0,0 +1,110 @@
+// protoc-gen-sol by Celer Network Team
+
+package generator
+
+import (
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// WktTypeMap maps supported well-known message types to solidity type, they're decoded by functions in library Pb
+// Timestamp and Duration are seconds, or nanoseconds if timenanos param is set. wrappers are the wrapped value
+var WktTypeMap = map[string]string{
+	".google.protobuf.Timestamp":   "uint",
+	".google.protobuf.Duration":    "uint",
+	".google.protobuf.UInt64Value": "uint64",
+	".google.protobuf.UInt32Value": "uint32",
+	".google.protobuf.Int64Value":  "int64",
+	".google.protobuf.Int32Value":  "int32",
+	".google.protobuf.BoolValue":   "bool",
+	".google.protobuf.StringValue": "string",
+	".google.protobuf.BytesValue":  "bytes",
+	".google.protobuf.Any":         "Any", // struct Any in library Pb
+}
+
+// isWkt returns whether field is a supported well-known message type
+func isWkt(field *descriptor.FieldDescriptorProto) bool {
+	_, ok := WktTypeMap[field.GetTypeName()]
+	return ok && field.GetType() == descriptor.FieldDescriptorProto_TYPE_MESSAGE
+}
+
+// hasPresence returns whether non-repeated wrapper field has an additional bool member for presence,
+// as a zero value and an unset wrapper are different
+func hasPresence(field *descriptor.FieldDescriptorProto) bool {
+	return isWkt(field) && strings.HasSuffix(field.GetTypeName(), "Value") && !isRepeated(field)
+}
+
+// presenceName returns struct member name of presence flag of field, eg. hasFee for field fee
+func (g *Generator) presenceName(field *descriptor.FieldDescriptorProto) string {
+	name := g.fieldName(field)
+	if g.naming == "snake" || g.naming == "proto" {
+		return "has_" + name
+	}
+	return "has" + strings.ToUpper(name[:1]) + name[1:]
+}
+
+// getWktConv returns solidity expression decoding well-known type from bytes expr
+func (g *Generator) getWktConv(field *descriptor.FieldDescriptorProto, expr string) string {
+	switch WktTypeMap[field.GetTypeName()] {
+	case "uint": // Timestamp or Duration
+		if g.timeNanos {
+			return g.pblib + "._timeNanos(" + expr + ")"
+		}
+		return g.pblib + "._timeSeconds(" + expr + ")"
+	case "uint64":
+		return "uint64(" + g.pblib + "._varintValue(" + expr + "))"
+	case "uint32":
+		return "uint32(" + g.pblib + "._varintValue(" + expr + "))"
+	case "int64": // negative is 64-bit two's complement on the wire
+		return "int64(uint64(" + g.pblib + "._varintValue(" + expr + ")))"
+	case "int32": // negative is sign extended to 64 bits on the wire
+		return "int32(int64(uint64(" + g.pblib + "._varintValue(" + expr + "))))"
+	case "bool":
+		return g.pblib + "._varintValue(" + expr + ") != 0"
+	case "string":
+		return "string(" + g.pblib + "._bytesValue(" + expr + "))"
+	case "Any":
+		return g.pblib + "._any(" + expr + ")"
+	}
+	return g.pblib + "._bytesValue(" + expr + ")"
+}
+
+// getWktType returns solidity type of well-known type field, without array suffix
+func (g *Generator) getWktType(field *descriptor.FieldDescriptorProto) string {
+	t := WktTypeMap[field.GetTypeName()]
+	if t == "Any" {
+		return g.pblib + ".Any"
+	}
+	return t
+}
+
+// generateAnyHelpers generates type url constant of message m, and functions to check and unpack google.protobuf.Any of m
+func (g *Generator) generateAnyHelpers(m msgdes) {
+	name := msgName(m)
+	fns := anyHelperNames(name)
+	g.P("// google.protobuf.Any type url of ", *m.Name)
+	g.P("string internal constant ", fns[0], ` = "`, g.typeURLPrefix, curPkg, ".", *m.Name, `";`, "\n")
+	g.P("// whether google.protobuf.Any a is ", *m.Name)
+	g.P("function ", fns[1], "(", g.pblib, ".Any memory a) internal pure returns (bool) {")
+	g.In()
+	g.P("return keccak256(bytes(a.typeUrl)) == keccak256(bytes(", fns[0], "));")
+	g.Out()
+	g.P("}\n")
+	g.P("// decode google.protobuf.Any a as ", *m.Name, ", reverts if it's not")
+	g.P("function ", fns[2], "(", g.pblib, ".Any memory a) internal pure returns (", name, " memory) {")
+	g.In()
+	if g.solc == "0.5" {
+		g.P("require(", fns[1], "(a));")
+	} else {
+		g.P("if (!", fns[1], "(a)) revert ", g.pblib, ".UnexpectedTypeUrl(a.typeUrl);")
+	}
+	g.P("return ", getDecFname(name), "(a.value);")
+	g.Out()
+	g.P("}\n")
+}
+
+// anyHelperNames returns names of TYPE_URL_Xxx, isXxx and unpackXxx of message named name
+func anyHelperNames(name string) []string {
+	return []string{"TYPE_URL_" + name, "is" + name, "unpack" + name}
+}
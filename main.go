@@ -24,6 +24,7 @@ func main() {
 	// so we can do error handling easily - the response structure contains the field to
 	// report failure.
 	g := generator.New()
+	g.Version, g.Commit = version, commit
 
 	data, err := ioutil.ReadAll(os.Stdin)
 	if err != nil {
@@ -34,6 +35,7 @@ func main() {
 		generator.Error(err, "parsing input proto")
 	}
 	g.ParseParams()
+	g.Preprocess()
 	g.GenerateAllFiles()
 
 	// Send back the results.
@@ -1,12 +1,16 @@
 #!/bin/bash
 
-# remove old sol and pb files
+# remove old sol, md and pb files
 rm -f *.pb
 rm -f solidity/contracts/lib/*.sol
+rm -f solidity/contracts/lib/*.md
 
-# generate new sol files
+# target solc version of generated sol files, 0.5 (default) or 0.8, eg. bash generate_sol_pb.sh 0.8
+solc=${1:-0.5}
+
+# generate new sol files, params are in config.json, solc in command line overrides it
 export PATH="$TRAVIS_BUILD_DIR:$PATH"
-protoc --sol_out=importpb=true:solidity/contracts/lib/ test.proto a.proto b.proto
+protoc -I. -I.. --sol_out=config=config.json,solc=$solc:solidity/contracts/lib/ test.proto a.proto b.proto opts.proto
 
 # generate new pb files
 for pathname in *.textpb; do
@@ -14,8 +18,12 @@ for pathname in *.textpb; do
     basename=${filename%.textpb}
     msg=${basename%%_*}
     msgno=${msg#msg}
-    cat "$basename".textpb|protoc  --encode=mytest.Msg"$msgno" test.proto > "$basename".pb || continue
+    cat "$basename".textpb|protoc -I. -I.. --encode=mytest.Msg"$msgno" test.proto > "$basename".pb || continue
 done
 
 # generate binary msg for b to test import
-protoc --encode=b.B b.proto < b.pbtxt > b.pb
+protoc -I. -I.. --encode=b.B b.proto < b.pbtxt > b.pb
+protoc -I. -I.. --encode=a.AOpen a.proto < a_open.pbtxt > a_open.pb
+
+# generate binary msg of opts to test scoped params
+protoc -I. -I.. --encode=opts.Order opts.proto < order.pbtxt > order.pb
@@ -1,4 +1,4 @@
-pragma solidity ^0.5.0;
+pragma solidity >=0.5.0 <0.9.0;
 
 contract Migrations {
   address public owner;
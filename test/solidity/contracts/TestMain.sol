@@ -1,8 +1,9 @@
-pragma solidity ^0.5.0;
+pragma solidity >=0.5.0 <0.9.0;  // also compiled with .sol generated by solc=0.8
 
 import "./lib/PbMytest.sol";
 import "./lib/PbA.sol";
 import "./lib/PbB.sol";
+import "./lib/PbOpts.sol";
 
 contract TestMain {
     event Msg1Part1(
@@ -47,6 +48,106 @@ contract TestMain {
         uint[] enums
     );
 
+    event Msg5Info(
+        address[2] peers,
+        uint8[3] nums,
+        uint32 m1f1_0,
+        uint32 m1f1_1
+    );
+
+    event Msg6Info(
+        uint e,
+        uint eNum,
+        uint[] es
+    );
+
+    event Msg7Info(
+        uint unknownVal,
+        uint rawVal,
+        uint rawValRaw,
+        uint strictVal
+    );
+
+    event Msg7Arrays(
+        uint[] rawVals,
+        uint[] rawValsRaw,
+        uint[2] fixedRawVals,
+        uint[2] fixedRawValsRaw
+    );
+
+    event Msg8Info(
+        uint ts,
+        uint dur,
+        uint64 fee,
+        bool hasFee,
+        bool hasTip,
+        string memo,
+        bytes data
+    );
+
+    event Msg8Any(
+        string typeUrl,
+        bool isMsg1,
+        bool isMsg2,
+        uint32 f1
+    );
+
+    event Msg8Ints(
+        int64 balance,
+        int32 delta,
+        bool hasBalance,
+        bool hasDelta
+    );
+
+    event Msg9Info(
+        bool markerPresent,
+        uint markersLen,
+        bool markers0Present,
+        uint64 last
+    );
+
+    event EmptyInfo(
+        bool present
+    );
+
+    event Msg10Info(
+        uint64 rootVal,
+        uint childrenLen,
+        uint64 child0Val,
+        uint64 grandchildVal,
+        uint64 child1Val
+    );
+
+    event Msg11Info(
+        uint64 f1,
+        uint64 f4
+    );
+
+    event Msg12Info(
+        uint64 address_,
+        uint64 from,
+        uint64 storage_,
+        uint64 buf,
+        uint64 tag,
+        uint64 wire,
+        uint cntsLen,
+        uint64 m
+    );
+
+    event OrderInfo(
+        uint64 orderId,
+        bool closed,
+        address owner
+    );
+
+    event StatusInfo(
+        string name,
+        uint num,
+        bool valid2,
+        bool valid3,
+        uint count
+    );
+
     event DecodedB (
         uint64 i,
         uint alistlen,
@@ -54,6 +155,12 @@ contract TestMain {
         uint elistlen
     );
 
+    event AOpenInfo(
+        uint unknownVal,
+        uint rawVal,
+        uint rawValRaw
+    );
+
     function testMsg1(bytes memory raw) public {
         PbMytest.Msg1 memory m = PbMytest.decMsg1(raw);
 
@@ -229,6 +336,134 @@ contract TestMain {
         );
     }
     
+    function testMsg5(bytes memory raw) public {
+        PbMytest.Msg5 memory m = PbMytest.decMsg5(raw);
+
+        emit Msg5Info(
+            m.peers,
+            m.nums,
+            m.m1s[0].f1,
+            m.m1s[1].f1
+        );
+    }
+
+    function testMsg6(bytes memory raw) public {
+        PbMytest.Msg6 memory m = PbMytest.decMsg6(raw);
+
+        uint[] memory uintEnums = new uint[](m.es.length);
+        for (uint i = 0; i < uintEnums.length; i++) { uintEnums[i] = uint(m.es[i]); }
+
+        emit Msg6Info(
+            uint(m.e),
+            PbMytest.gapEnumToNumber(m.e),
+            uintEnums
+        );
+    }
+
+    function testMsg7(bytes memory raw) public {
+        PbMytest.Msg7 memory m = PbMytest.decMsg7(raw);
+
+        emit Msg7Info(
+            uint(m.unknownVal),
+            uint(m.rawVal),
+            m.rawValRaw,
+            uint(m.strictVal)
+        );
+
+        uint[] memory uintEnums = new uint[](m.rawVals.length);
+        for (uint i = 0; i < uintEnums.length; i++) { uintEnums[i] = uint(m.rawVals[i]); }
+        uint[2] memory fixedEnums = [uint(m.fixedRawVals[0]), uint(m.fixedRawVals[1])];
+
+        emit Msg7Arrays(
+            uintEnums,
+            m.rawValsRaw,
+            fixedEnums,
+            m.fixedRawValsRaw
+        );
+    }
+
+    function testMsg8(bytes memory raw) public {
+        PbMytest.Msg8 memory m = PbMytest.decMsg8(raw);
+
+        emit Msg8Info(
+            m.ts,
+            m.dur,
+            m.fee,
+            m.hasFee,
+            m.hasTip,
+            m.memo,
+            m.data
+        );
+
+        emit Msg8Any(
+            m.any.typeUrl,
+            PbMytest.isMsg1(m.any),
+            PbMytest.isMsg2(m.any),
+            PbMytest.unpackMsg1(m.any).f1  // reverts if any isn't Msg1
+        );
+
+        emit Msg8Ints(
+            m.balance,
+            m.delta,
+            m.hasBalance,
+            m.hasDelta
+        );
+    }
+
+    function testMsg9(bytes memory raw) public {
+        PbMytest.Msg9 memory m = PbMytest.decMsg9(raw);
+
+        emit Msg9Info(
+            m.marker.present,
+            m.markers.length,
+            m.markers[0].present,
+            m.last
+        );
+    }
+
+    function testMsg10(bytes memory raw) public {
+        PbMytest.Msg10 memory m = PbMytest.decMsg10(raw);
+        // children are raw bytes, decoded lazily
+        PbMytest.Node memory child0 = PbMytest.decNode(m.root.children[0]);
+
+        emit Msg10Info(
+            m.root.val,
+            m.root.children.length,
+            child0.val,
+            PbMytest.decNode(child0.children[0]).val,
+            PbMytest.decNode(m.root.children[1]).val
+        );
+    }
+
+    function testMsg11(bytes memory raw) public {
+        PbMytest.Msg11 memory m = PbMytest.decMsg11(raw);
+
+        emit Msg11Info(
+            m.f1,
+            m.f4
+        );
+    }
+
+    function testMsg12(bytes memory raw) public {
+        PbMytest.Msg12 memory m = PbMytest.decMsg12(raw);
+
+        emit Msg12Info(
+            m.address_,
+            m.from,
+            m.storage_,
+            m.buf,
+            m.tag,
+            m.wire,
+            m.cnts.length,
+            m.m
+        );
+    }
+
+    // decode any message as Empty, its fields are skipped
+    function testEmpty(bytes memory raw) public {
+        emit EmptyInfo(PbMytest.decEmpty(raw).present);
+    }
+
     function testImport(bytes memory raw) public {
         PbB.B memory m = PbB.decB(raw);
         emit DecodedB(
@@ -238,4 +473,32 @@ contract TestMain {
             m.elist.length
         );
     }
-}
\ No newline at end of file
+
+    function testAOpen(bytes memory raw) public {
+        PbA.AOpen memory m = PbA.decAOpen(raw);
+        emit AOpenInfo(
+            uint(m.unknownVal),
+            uint(m.rawVal),
+            m.rawValRaw
+        );
+    }
+
+    // opts has scoped params naming snake, stripenumprefix and enumutils in config.json
+    function testOrder(bytes memory raw) public {
+        PbOpts.Order memory m = PbOpts.decOrder(raw);
+        emit OrderInfo(
+            m.order_id,
+            m.status == PbOpts.Status.CLOSED,
+            m.owner_addr
+        );
+
+        // enum utility functions by scoped enumutils
+        emit StatusInfo(
+            PbOpts.statusToString(m.status),
+            PbOpts.statusToNumber(m.status),
+            PbOpts.isValidStatus(2),
+            PbOpts.isValidStatus(3),
+            PbOpts.statusCount()
+        );
+    }
+}
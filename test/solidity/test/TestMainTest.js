@@ -245,6 +245,235 @@ contract('TestMain', async accounts => {
         assert.equal(receipt.logs[0].args.enums.toString(), [0, 1, 2]);
     });
 
+    it('should decode msg5 (fixed-size arrays) correctly', async () => {
+        const buf = fs.readFileSync(path.join(__dirname, "../../msg5.pb"));
+        const raw = '0x' + buf.toString('hex');
+
+        const receipt = await testMain.testMsg5(raw);
+
+        assert.equal(receipt.logs[0].event, 'Msg5Info');
+        const expectedPeers = ['0x0002030405060708090a0b0c0d0e0f1011121314','0x0b0c0d0e0f10111213140102030405060708090a'];
+        assert.equal(receipt.logs[0].args.peers.toString().toLowerCase(), expectedPeers);
+        assert.equal(receipt.logs[0].args.nums.toString(), [12, 34, 56]);
+        assert.equal(receipt.logs[0].args.m1f1_0.toString(), '1');
+        assert.equal(receipt.logs[0].args.m1f1_1.toString(), '2');
+    });
+
+    it('should not decode msg5 with wrong array size successfully', async () => {
+        const buf = fs.readFileSync(path.join(__dirname, "../../msg5_wrong_count.pb"));
+        const raw = '0x' + buf.toString('hex');
+
+        let err = null;
+
+        try {
+            await testMain.testMsg5(raw);
+        } catch (error) {
+            err = error;
+        }
+        assert.isOk(err instanceof Error);
+    });
+
+    it('should decode msg6 (enum with gaps, negative number and alias) correctly', async () => {
+        const buf = fs.readFileSync(path.join(__dirname, "../../msg6.pb"));
+        const raw = '0x' + buf.toString('hex');
+
+        const receipt = await testMain.testMsg6(raw);
+
+        assert.equal(receipt.logs[0].event, 'Msg6Info');
+        // solidity enum members are GAP_ZERO, GAP_HUNDRED, GAP_NEG, alias GAP_ALIAS is GAP_HUNDRED
+        assert.equal(receipt.logs[0].args.e.toString(), '2');
+        assert.equal(receipt.logs[0].args.eNum.toString(), '18446744073709551615');
+        assert.equal(receipt.logs[0].args.es.toString(), [0, 1, 2, 1]);
+    });
+
+    it('should not decode msg6 with undefined enum number successfully', async () => {
+        const buf = fs.readFileSync(path.join(__dirname, "../../msg6_undefined.pb"));
+        const raw = '0x' + buf.toString('hex');
+
+        let err = null;
+
+        try {
+            await testMain.testMsg6(raw);
+        } catch (error) {
+            err = error;
+        }
+        assert.isOk(err instanceof Error);
+    });
+
+    it('should decode msg7 (unknown enum numbers of open enum) correctly', async () => {
+        const buf = fs.readFileSync(path.join(__dirname, "../../msg7.pb"));
+        const raw = '0x' + buf.toString('hex');
+
+        const receipt = await testMain.testMsg7(raw);
+
+        // unknown numbers are OPEN_UNKNOWN (0), raw mode also keeps the number
+        assert.equal(receipt.logs[0].event, 'Msg7Info');
+        assert.equal(receipt.logs[0].args.unknownVal.toString(), '0');
+        assert.equal(receipt.logs[0].args.rawVal.toString(), '0');
+        assert.equal(receipt.logs[0].args.rawValRaw.toString(), '7');
+        assert.equal(receipt.logs[0].args.strictVal.toString(), '2');
+
+        assert.equal(receipt.logs[1].event, 'Msg7Arrays');
+        assert.equal(receipt.logs[1].args.rawVals.toString(), [1, 0]);
+        assert.equal(receipt.logs[1].args.rawValsRaw.toString(), [1, 9]);
+        assert.equal(receipt.logs[1].args.fixedRawVals.toString(), [2, 0]);
+        assert.equal(receipt.logs[1].args.fixedRawValsRaw.toString(), [2, 8]);
+    });
+
+    it('should not decode msg7 with unknown number of revert mode field successfully', async () => {
+        const buf = fs.readFileSync(path.join(__dirname, "../../msg7_strict_unknown.pb"));
+        const raw = '0x' + buf.toString('hex');
+
+        let err = null;
+
+        try {
+            await testMain.testMsg7(raw);
+        } catch (error) {
+            err = error;
+        }
+        assert.isOk(err instanceof Error);
+    });
+
+    it('should decode msg8 (well-known types) correctly', async () => {
+        const buf = fs.readFileSync(path.join(__dirname, "../../msg8.pb"));
+        const raw = '0x' + buf.toString('hex');
+
+        const receipt = await testMain.testMsg8(raw);
+
+        assert.equal(receipt.logs[0].event, 'Msg8Info');
+        assert.equal(receipt.logs[0].args.ts.toString(), '1600000000');  // nanos are dropped
+        assert.equal(receipt.logs[0].args.dur.toString(), '60');
+        assert.equal(receipt.logs[0].args.fee.toString(), '12');
+        assert.equal(receipt.logs[0].args.hasFee.toString(), 'true');
+        assert.equal(receipt.logs[0].args.hasTip.toString(), 'false');
+        assert.equal(receipt.logs[0].args.memo.toString(), 'hi');
+        assert.equal(receipt.logs[0].args.data.toString(), '0x0102');
+
+        assert.equal(receipt.logs[1].event, 'Msg8Any');
+        assert.equal(receipt.logs[1].args.typeUrl.toString(), 'type.googleapis.com/mytest.Msg1');
+        assert.equal(receipt.logs[1].args.isMsg1.toString(), 'true');
+        assert.equal(receipt.logs[1].args.isMsg2.toString(), 'false');
+        assert.equal(receipt.logs[1].args.f1.toString(), '7');
+
+        assert.equal(receipt.logs[2].event, 'Msg8Ints');
+        assert.equal(receipt.logs[2].args.balance.toString(), '-5');
+        assert.equal(receipt.logs[2].args.delta.toString(), '-3');
+        assert.equal(receipt.logs[2].args.hasBalance.toString(), 'true');
+        assert.equal(receipt.logs[2].args.hasDelta.toString(), 'true');
+    });
+
+    it('should not unpack msg8 any of another message type successfully', async () => {
+        const buf = fs.readFileSync(path.join(__dirname, "../../msg8_wrong_any.pb"));
+        const raw = '0x' + buf.toString('hex');
+
+        let err = null;
+
+        try {
+            await testMain.testMsg8(raw);
+        } catch (error) {
+            err = error;
+        }
+        assert.isOk(err instanceof Error);
+    });
+
+    it('should not decode msg8 with negative duration successfully', async () => {
+        const buf = fs.readFileSync(path.join(__dirname, "../../msg8_negative_duration.pb"));
+        const raw = '0x' + buf.toString('hex');
+
+        let err = null;
+
+        try {
+            await testMain.testMsg8(raw);
+        } catch (error) {
+            err = error;
+        }
+        assert.isOk(err instanceof Error);
+    });
+
+    it('should not decode msg8 with invalid nanos successfully', async () => {
+        const buf = fs.readFileSync(path.join(__dirname, "../../msg8_invalid_nanos.pb"));
+        const raw = '0x' + buf.toString('hex');
+
+        let err = null;
+
+        try {
+            await testMain.testMsg8(raw);
+        } catch (error) {
+            err = error;
+        }
+        assert.isOk(err instanceof Error);
+    });
+
+    it('should decode msg9 (empty messages) correctly', async () => {
+        const buf = fs.readFileSync(path.join(__dirname, "../../msg9.pb"));
+        const raw = '0x' + buf.toString('hex');
+
+        const receipt = await testMain.testMsg9(raw);
+
+        assert.equal(receipt.logs[0].event, 'Msg9Info');
+        assert.equal(receipt.logs[0].args.markerPresent.toString(), 'true');
+        assert.equal(receipt.logs[0].args.markersLen.toString(), '2');
+        assert.equal(receipt.logs[0].args.markers0Present.toString(), 'true');
+        assert.equal(receipt.logs[0].args.last.toString(), '9');
+    });
+
+    it('should decode msg10 (recursive message) correctly', async () => {
+        const buf = fs.readFileSync(path.join(__dirname, "../../msg10.pb"));
+        const raw = '0x' + buf.toString('hex');
+
+        const receipt = await testMain.testMsg10(raw);
+
+        assert.equal(receipt.logs[0].event, 'Msg10Info');
+        assert.equal(receipt.logs[0].args.rootVal.toString(), '1');
+        assert.equal(receipt.logs[0].args.childrenLen.toString(), '2');
+        assert.equal(receipt.logs[0].args.child0Val.toString(), '2');
+        assert.equal(receipt.logs[0].args.grandchildVal.toString(), '4');
+        assert.equal(receipt.logs[0].args.child1Val.toString(), '3');
+    });
+
+    it('should decode msg11 (skipped fields) correctly', async () => {
+        const buf = fs.readFileSync(path.join(__dirname, "../../msg11.pb"));
+        const raw = '0x' + buf.toString('hex');
+
+        const receipt = await testMain.testMsg11(raw);
+
+        // sol_skip and deprecated fields before f4 are skipped as unknown fields
+        assert.equal(receipt.logs[0].event, 'Msg11Info');
+        assert.equal(receipt.logs[0].args.f1.toString(), '1');
+        assert.equal(receipt.logs[0].args.f4.toString(), '4');
+
+        const sol = fs.readFileSync(path.join(__dirname, "../contracts/lib/PbMytest.sol"), 'utf8');
+        assert.notInclude(sol, 'string memo;');
+        assert.notInclude(sol, 'oldIds');
+    });
+
+    it('should decode msg12 (keyword and decoder local names) correctly', async () => {
+        const buf = fs.readFileSync(path.join(__dirname, "../../msg12.pb"));
+        const raw = '0x' + buf.toString('hex');
+
+        const receipt = await testMain.testMsg12(raw);
+
+        assert.equal(receipt.logs[0].event, 'Msg12Info');
+        assert.equal(receipt.logs[0].args.address_.toString(), '1');
+        assert.equal(receipt.logs[0].args.from.toString(), '2');
+        assert.equal(receipt.logs[0].args.storage_.toString(), '3');
+        assert.equal(receipt.logs[0].args.buf.toString(), '4');
+        assert.equal(receipt.logs[0].args.tag.toString(), '5');
+        assert.equal(receipt.logs[0].args.wire.toString(), '6');
+        assert.equal(receipt.logs[0].args.cntsLen.toString(), '2');
+        assert.equal(receipt.logs[0].args.m.toString(), '8');
+    });
+
+    it('should skip fields of msg1 decoded as empty message', async () => {
+        const buf = fs.readFileSync(path.join(__dirname, "../../msg1.pb"));
+        const raw = '0x' + buf.toString('hex');
+
+        const receipt = await testMain.testEmpty(raw);
+
+        assert.equal(receipt.logs[0].event, 'EmptyInfo');
+        assert.equal(receipt.logs[0].args.present.toString(), 'true');
+    });
+
     it('should decode import correctly', async () => {
         const buf = fs.readFileSync(path.join(__dirname, "../../b.pb"));
         const raw = '0x' + buf.toString('hex');
@@ -257,4 +486,75 @@ contract('TestMain', async accounts => {
         assert.equal(receipt.logs[0].args.e.toString(), '0');
         assert.equal(receipt.logs[0].args.elistlen.toString(), '2');
     });
+
+    it('should decode open enum fields of enum in another package correctly', async () => {
+        const buf = fs.readFileSync(path.join(__dirname, "../../a_open.pb"));
+        const raw = '0x' + buf.toString('hex');
+
+        const receipt = await testMain.testAOpen(raw);
+
+        // 5 isn't a number of mytest.EnumExample, it's Type0 via PbMytest conversion functions
+        assert.equal(receipt.logs[0].event, 'AOpenInfo');
+        assert.equal(receipt.logs[0].args.unknownVal.toString(), '0');
+        assert.equal(receipt.logs[0].args.rawVal.toString(), '0');
+        assert.equal(receipt.logs[0].args.rawValRaw.toString(), '5');
+    });
+
+    it('should decode order of package with scoped params correctly', async () => {
+        const buf = fs.readFileSync(path.join(__dirname, "../../order.pb"));
+        const raw = '0x' + buf.toString('hex');
+
+        const receipt = await testMain.testOrder(raw);
+
+        assert.equal(receipt.logs[0].event, 'OrderInfo');
+        assert.equal(receipt.logs[0].args.orderId.toString(), '7');
+        assert.equal(receipt.logs[0].args.closed.toString(), 'true');
+        assert.equal(receipt.logs[0].args.owner.toString().toLowerCase(), '0x0102030405060708090a0b0c0d0e0f1011121314');
+
+        assert.equal(receipt.logs[1].event, 'StatusInfo');
+        assert.equal(receipt.logs[1].args.name.toString(), 'STATUS_CLOSED');
+        assert.equal(receipt.logs[1].args.num.toString(), '2');
+        assert.equal(receipt.logs[1].args.valid2.toString(), 'true');
+        assert.equal(receipt.logs[1].args.valid3.toString(), 'false');
+        assert.equal(receipt.logs[1].args.count.toString(), '3');
+    });
+
+    it('should apply params scoped to package or .proto file', async () => {
+        const opts = fs.readFileSync(path.join(__dirname, "../contracts/lib/PbOpts.sol"), 'utf8');
+        const mytest = fs.readFileSync(path.join(__dirname, "../contracts/lib/PbMytest.sol"), 'utf8');
+
+        // spdx and provenance of opts
+        assert.isOk(opts.startsWith('// SPDX-License-Identifier: MIT\n'));
+        assert.include(opts, '// opts.proto descriptor sha256: ');
+        assert.include(opts, '// params: config=config.json');
+        assert.notInclude(mytest, 'SPDX-License-Identifier: MIT');
+        assert.notInclude(mytest, 'descriptor sha256');
+        // exclude of opts.proto
+        assert.notInclude(opts, 'struct Unused');
+        // anyhelpers of mytest only
+        assert.include(mytest, 'TYPE_URL_Msg1');
+        assert.notInclude(opts, 'TYPE_URL_');
+    });
+
+    it('should name runtime library by pblib', async () => {
+        const lib = path.join(__dirname, "../contracts/lib");
+        const runtime = fs.readFileSync(path.join(lib, "PbRuntime.sol"), 'utf8');
+        const mytest = fs.readFileSync(path.join(lib, "PbMytest.sol"), 'utf8');
+
+        assert.include(runtime, 'library PbRuntime {');
+        assert.isNotOk(fs.existsSync(path.join(lib, "Pb.sol")));
+        assert.include(mytest, 'import "./PbRuntime.sol";');
+        assert.include(mytest, 'using PbRuntime for PbRuntime.Buffer;');
+        assert.notMatch(mytest, /[^\w]Pb\./);
+    });
+
+    it('should generate markdown reference of package opts', async () => {
+        const md = fs.readFileSync(path.join(__dirname, "../contracts/lib/PbOpts.md"), 'utf8');
+
+        assert.include(md, '## struct Order');
+        assert.include(md, '| 1 | `order_id` | `uint64` |  | `uint64` |  | `m.order_id = uint64(buf.decVarint());` |  |');
+        assert.include(md, '- `isValidStatus(uint num) returns (bool)`');
+        assert.notInclude(md, 'Unused');
+        assert.isNotOk(fs.existsSync(path.join(__dirname, "../contracts/lib/PbMytest.md")));
+    });
 });
@@ -19,3 +19,13 @@ module.exports = {
   }
   */
 };
+
+// SOLC_VERSION selects solc, eg. 0.8.13 to test .sol generated by generate_sol_pb.sh 0.8.
+// truffle's default solc is used if it's not set
+if (process.env.SOLC_VERSION) {
+  module.exports.compilers = {
+    solc: {
+      version: process.env.SOLC_VERSION
+    }
+  };
+}